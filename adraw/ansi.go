@@ -0,0 +1,209 @@
+package adraw
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Number of columns between tab stops when parsing text.
+const TabWidth = 8
+
+// Parses text that may contain ANSI SGR escape codes, such as the output of ExportANSI or of
+// another terminal program, into a grid of cells. Text before the first color code and after
+// a reset takes the given base style. Colors outside the 16 ANSI colors are approximated by
+// the closest of them, and other escape sequences are skipped.
+//
+// Lines may end in LF, CRLF or a lone CR, and tabs are expanded to the next multiple of
+// TabWidth. Characters outside 7-bit ASCII become '?'. The grid is as wide as the longest
+// line, with shorter lines padded by blank cells.
+func ParseANSI(text string, base tcell.Style) Grid[Cell] {
+	p := ansiParser{base: base, style: base}
+	p.lines = [][]Cell{nil}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\x1b':
+			i = p.escape(runes, i)
+		case r == '\r':
+			if i+1 < len(runes) && runes[i+1] == '\n' {
+				i++
+			}
+			p.newline()
+		case r == '\n':
+			p.newline()
+		case r == '\t':
+			p.put(' ')
+			for len(p.lines[len(p.lines)-1])%TabWidth != 0 {
+				p.put(' ')
+			}
+		case r < ' ' || r == 0x7f:
+			// Other control characters have no place on the canvas
+		case r > 0x7f:
+			p.put('?')
+		default:
+			p.put(byte(r))
+		}
+	}
+
+	// A trailing newline does not start another line
+	if len(p.lines) > 1 && len(p.lines[len(p.lines)-1]) == 0 {
+		p.lines = p.lines[:len(p.lines)-1]
+	}
+
+	width := 1
+	for _, ln := range p.lines {
+		width = max(width, len(ln))
+	}
+	g := MakeGrid(width, len(p.lines), Cell{Value: ' '})
+	for y, ln := range p.lines {
+		for x, c := range ln {
+			g.Set(x, y, c)
+		}
+	}
+	return g
+}
+
+type ansiParser struct {
+	base  tcell.Style
+	style tcell.Style
+	// Whether bold is on, which brightens the 8 basic foreground colors like most terminals
+	// do.
+	bold  bool
+	lines [][]Cell
+}
+
+func (p *ansiParser) put(v byte) {
+	ln := &p.lines[len(p.lines)-1]
+	*ln = append(*ln, Cell{Value: v, Style: p.style})
+}
+
+func (p *ansiParser) newline() {
+	p.lines = append(p.lines, nil)
+}
+
+// Handles the escape sequence starting at runes[i], returning the index of its last rune.
+func (p *ansiParser) escape(runes []rune, i int) int {
+	if i+1 >= len(runes) {
+		return i
+	}
+	switch runes[i+1] {
+	case '[':
+		// CSI: parameters and intermediates up to a final byte in @ to ~
+		end := i + 2
+		for end < len(runes) && (runes[end] < '@' || runes[end] > '~') {
+			end++
+		}
+		if end >= len(runes) {
+			return len(runes) - 1
+		}
+		if runes[end] == 'm' {
+			p.sgr(string(runes[i+2 : end]))
+		}
+		return end
+	case ']':
+		// OSC: up to BEL or ST
+		for end := i + 2; end < len(runes); end++ {
+			if runes[end] == '\a' {
+				return end
+			}
+			if runes[end] == '\x1b' && end+1 < len(runes) && runes[end+1] == '\\' {
+				return end + 1
+			}
+		}
+		return len(runes) - 1
+	default:
+		return i + 1
+	}
+}
+
+// Applies the parameters of an SGR sequence.
+func (p *ansiParser) sgr(params string) {
+	var codes []int
+	for _, s := range strings.FieldsFunc(params, func(r rune) bool { return r == ';' || r == ':' }) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return
+		}
+		codes = append(codes, n)
+	}
+	if len(codes) == 0 {
+		codes = []int{0}
+	}
+
+	fg, bg, _ := p.style.Decompose()
+	for i := 0; i < len(codes); i++ {
+		switch c := codes[i]; {
+		case c == 0:
+			fg, bg, _ = p.base.Decompose()
+			p.bold = false
+		case c == 1:
+			p.bold = true
+			if fg >= tcell.ColorValid && fg < tcell.ColorValid+8 {
+				fg += 8
+			}
+		case c == 22:
+			p.bold = false
+		case c >= 30 && c <= 37:
+			fg = tcell.ColorValid + tcell.Color(c-30)
+			if p.bold {
+				fg += 8
+			}
+		case c >= 90 && c <= 97:
+			fg = tcell.ColorValid + tcell.Color(c-90+8)
+		case c == 39:
+			fg = tcell.ColorDefault
+		case c >= 40 && c <= 47:
+			bg = tcell.ColorValid + tcell.Color(c-40)
+		case c >= 100 && c <= 107:
+			bg = tcell.ColorValid + tcell.Color(c-100+8)
+		case c == 49:
+			bg = tcell.ColorDefault
+		case c == 38 || c == 48:
+			col, n := extendedColor(codes[i+1:])
+			i += n
+			if n == 0 {
+				break
+			}
+			if c == 38 {
+				fg = col
+			} else {
+				bg = col
+			}
+		}
+	}
+	p.style = tcell.StyleDefault.Foreground(fg).Background(bg)
+}
+
+// Reads a 256-color (5;n) or true color (2;r;g;b) argument of SGR 38 and 48, returning the
+// closest ANSI color and the number of codes used.
+func extendedColor(codes []int) (tcell.Color, int) {
+	if len(codes) >= 2 && codes[0] == 5 {
+		return closestANSIColor(tcell.PaletteColor(codes[1])), 2
+	}
+	if len(codes) >= 4 && codes[0] == 2 {
+		return closestANSIColor(tcell.NewRGBColor(int32(codes[1]), int32(codes[2]), int32(codes[3]))), 4
+	}
+	return tcell.ColorDefault, 0
+}
+
+// The one of the 16 ANSI colors that is closest to c.
+func closestANSIColor(c tcell.Color) tcell.Color {
+	if c >= tcell.ColorValid && c < tcell.ColorValid+16 {
+		return c
+	}
+	r, g, b := c.RGB()
+	best, bestDist := tcell.ColorDefault, int32(-1)
+	for i := range 16 {
+		cand := tcell.ColorValid + tcell.Color(i)
+		cr, cg, cb := cand.RGB()
+		d := (r-cr)*(r-cr) + (g-cg)*(g-cg) + (b-cb)*(b-cb)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = cand, d
+		}
+	}
+	return best
+}
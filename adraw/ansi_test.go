@@ -0,0 +1,77 @@
+package adraw
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseANSI(t *testing.T) {
+	base := tcell.StyleDefault.Foreground(tcell.ColorYellow)
+	g := ParseANSI("a\x1b[31;44mb\x1b[0mc\r\n\td\x1b[1;32me\re\xe9\n", base)
+
+	if g.Width != 10 || g.Height != 3 {
+		t.Fatalf("grid is %d x %d, want 10 x 3", g.Width, g.Height)
+	}
+	tests := []struct {
+		x, y   int
+		v      byte
+		fg, bg tcell.Color
+	}{
+		{0, 0, 'a', tcell.ColorYellow, tcell.ColorDefault},
+		{1, 0, 'b', tcell.ColorMaroon, tcell.ColorNavy},
+		{2, 0, 'c', tcell.ColorYellow, tcell.ColorDefault},
+		{8, 1, 'd', tcell.ColorYellow, tcell.ColorDefault},
+		{9, 1, 'e', tcell.ColorLime, tcell.ColorDefault},
+		{0, 2, 'e', tcell.ColorLime, tcell.ColorDefault},
+		{1, 2, '?', tcell.ColorLime, tcell.ColorDefault},
+	}
+	for _, tt := range tests {
+		c := g.MustGet(tt.x, tt.y)
+		fg, bg, _ := c.Style.Decompose()
+		if c.Value != tt.v || fg != tt.fg || bg != tt.bg {
+			t.Errorf("cell (%d, %d) is %q %v/%v, want %q %v/%v", tt.x, tt.y, c.Value, fg, bg, tt.v, tt.fg, tt.bg)
+		}
+	}
+	if c := g.MustGet(5, 0); c.Value != ' ' {
+		t.Errorf("short line is padded with %q", c.Value)
+	}
+}
+
+func TestParseANSIExtendedColors(t *testing.T) {
+	g := ParseANSI("\x1b[38;5;196;48;2;0;0;250mx\x1b]0;title\x07y", tcell.StyleDefault)
+	fg, bg, _ := g.MustGet(0, 0).Style.Decompose()
+	if fg != tcell.ColorRed || bg != tcell.ColorBlue {
+		t.Errorf("colors are %v/%v, want red/blue", fg, bg)
+	}
+	if g.Width != 2 || g.MustGet(1, 0).Value != 'y' {
+		t.Errorf("title sequence was not skipped")
+	}
+}
+
+func TestParseANSIRoundTrip(t *testing.T) {
+	b := MakeBuffer(4, 2)
+	b.Set(0, 0, 'a', tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorTeal))
+	b.Set(3, 1, 'b', tcell.StyleDefault.Foreground(tcell.ColorNavy))
+
+	var buf bytes.Buffer
+	if err := b.ExportANSI(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g := ParseANSI(buf.String(), tcell.StyleDefault.Foreground(tcell.ColorYellow))
+	if g.Width != 4 || g.Height != 2 {
+		t.Fatalf("grid is %d x %d, want 4 x 2", g.Width, g.Height)
+	}
+	for y := range 2 {
+		for x := range 4 {
+			want, got := b.Data.MustGet(x, y), g.MustGet(x, y)
+			if want.Value == 0 {
+				want.Value = ' '
+			}
+			if got != want {
+				t.Errorf("cell (%d, %d) is %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
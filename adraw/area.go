@@ -1,4 +1,4 @@
-package main
+package adraw
 
 type Area struct {
 	X      int
@@ -35,6 +35,21 @@ func (a Area) Intersection(other Area) (Area, bool) {
 	}, true
 }
 
+// Returns the smallest area containing both areas.
+func (a Area) Union(other Area) Area {
+	top := min(a.Top(), other.Top())
+	bottom := max(a.Bottom(), other.Bottom())
+	left := min(a.Left(), other.Left())
+	right := max(a.Right(), other.Right())
+
+	return Area{
+		X:      left,
+		Y:      top,
+		Width:  right - left,
+		Height: bottom - top,
+	}
+}
+
 func (a Area) Contains(x, y int) bool {
 	nx := x - a.X
 	ny := y - a.Y
@@ -0,0 +1,107 @@
+package adraw
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+)
+
+// Outline of the cells a brush paints around its center.
+type BrushShape int
+
+const (
+	BrushSquare BrushShape = iota
+	BrushCircle
+	BrushDiamond
+	// A mask taken from a selection, see SelectionBrush.
+	BrushCustom
+)
+
+var brushShapeNames = [...]string{"square", "circle", "diamond", "custom"}
+
+func (s BrushShape) String() string {
+	return brushShapeNames[s]
+}
+
+func ParseBrushShape(s string) (BrushShape, error) {
+	idx := slices.Index(brushShapeNames[:], s)
+	if idx == -1 {
+		return BrushSquare, fmt.Errorf("invalid brush shape %q (want square, circle, diamond or custom)", s)
+	}
+	return BrushShape(idx), nil
+}
+
+// Mask of a brush of the given shape that is size cells across. The center of the brush is
+// the cell at (Width/2, Height/2). BrushCustom has no mask of its own and gives a square.
+func BrushMask(shape BrushShape, size int) Grid[bool] {
+	size = max(1, size)
+	// Distances are measured from the middle of the mask, which lies between two cells for
+	// even sizes
+	c := float64(size-1) / 2
+	return MakeGridWith(size, size, func(x, y int) bool {
+		dx, dy := float64(x)-c, float64(y)-c
+		switch shape {
+		case BrushCircle:
+			// Slightly inside the edge, so that small circles are not squares
+			r := float64(size)/2 - 0.25
+			return dx*dx+dy*dy <= r*r
+		case BrushDiamond:
+			return max(dx, -dx)+max(dy, -dy) <= float64(size/2)
+		default:
+			return true
+		}
+	})
+}
+
+// Brush mask with the shape of the selection, cropped to its bounds. Returns false if
+// nothing is selected.
+func (b *Buffer) SelectionBrush() (Grid[bool], bool) {
+	if !b.HasSelection() {
+		return Grid[bool]{}, false
+	}
+	bounds := b.SelectionBounds()
+	brush := MakeGridWith(bounds.Width, bounds.Height, func(x, y int) bool {
+		return b.SelectionMask.MustGet(x+bounds.X, y+bounds.Y)
+	})
+	for y := range brush.Height {
+		for x := range brush.Width {
+			if brush.MustGet(x, y) {
+				return brush, true
+			}
+		}
+	}
+	return Grid[bool]{}, false
+}
+
+// Paints the cells of the brush mask, with the center of the brush at the given position.
+func (b *Buffer) PaintBrush(brush Grid[bool], center Position, cell Cell, mask LockMask) {
+	left, top := center.X-brush.Width/2, center.Y-brush.Height/2
+	for y := range brush.Height {
+		for x := range brush.Width {
+			if brush.MustGet(x, y) {
+				b.setCellMirrored(left+x, top+y, cell, mask)
+			}
+		}
+	}
+}
+
+// Paints count cells of the brush mask picked at random, with the center of the brush at the
+// given position. The same cell may be picked more than once.
+func (b *Buffer) Spray(brush Grid[bool], center Position, count int, rng *rand.Rand, cell Cell, mask LockMask) {
+	var cells []Position
+	for y := range brush.Height {
+		for x := range brush.Width {
+			if brush.MustGet(x, y) {
+				cells = append(cells, Position{X: x, Y: y})
+			}
+		}
+	}
+	if len(cells) == 0 {
+		return
+	}
+	left, top := center.X-brush.Width/2, center.Y-brush.Height/2
+	for range count {
+		pt := cells[rng.IntN(len(cells))]
+		b.setCellMirrored(left+pt.X, top+pt.Y, cell, mask)
+	}
+}
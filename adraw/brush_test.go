@@ -0,0 +1,69 @@
+package adraw
+
+import (
+	"strings"
+	"testing"
+)
+
+func maskString(g Grid[bool]) string {
+	var sb strings.Builder
+	for y := range g.Height {
+		for x := range g.Width {
+			if g.MustGet(x, y) {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func TestBrushMask(t *testing.T) {
+	tests := []struct {
+		shape BrushShape
+		size  int
+		want  string
+	}{
+		{BrushSquare, 2, "##\n##\n"},
+		{BrushCircle, 1, "#\n"},
+		{BrushCircle, 3, ".#.\n###\n.#.\n"},
+		{BrushCircle, 5, ".###.\n#####\n#####\n#####\n.###.\n"},
+		{BrushDiamond, 5, "..#..\n.###.\n#####\n.###.\n..#..\n"},
+		{BrushDiamond, 4, ".##.\n####\n####\n.##.\n"},
+	}
+	for _, tt := range tests {
+		if got := maskString(BrushMask(tt.shape, tt.size)); got != tt.want {
+			t.Errorf("%v brush of size %d is\n%swant\n%s", tt.shape, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestSelectionBrush(t *testing.T) {
+	b := MakeBuffer(6, 4)
+	if _, ok := b.SelectionBrush(); ok {
+		t.Error("got a brush without a selection")
+	}
+
+	sel := MakeGrid(2, 2, true)
+	sel.Set(1, 1, false)
+	b.SetSelection(sel, Position{X: 3, Y: 1})
+	brush, ok := b.SelectionBrush()
+	if !ok {
+		t.Fatal("got no brush from the selection")
+	}
+	if got, want := maskString(brush), "##\n#.\n"; got != want {
+		t.Errorf("brush is\n%swant\n%s", got, want)
+	}
+
+	b.Deselect()
+	// The center of a 2 x 2 brush is its bottom-right cell
+	b.PaintBrush(brush, Position{X: 1, Y: 1}, Cell{Value: '#'}, 0)
+	if got := b.Data.MustGet(0, 1).Value; got != '#' {
+		t.Errorf("cell of the brush is %q, want '#'", got)
+	}
+	if got := b.Data.MustGet(1, 1).Value; got != ' ' {
+		t.Errorf("cell outside the brush is %q, want ' '", got)
+	}
+}
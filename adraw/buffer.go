@@ -1,4 +1,4 @@
-package main
+package adraw
 
 import (
 	"encoding/binary"
@@ -12,7 +12,23 @@ import (
 
 type CellColor byte
 
-const magicNumber int64 = 0xdeadbeef
+// Magic number at the start of every binary ascii-draw file.
+const MagicNumber int64 = 0xdeadbeef
+
+type LockMask int
+
+const (
+	// If set, makes painting operations ignore empty cells. Empty cells are cells where the
+	// character is the space character (` `) and where the background color is the terminal
+	// default.
+	LockMaskAlpha LockMask = 1 << iota
+	// If set, painting operations do not modify the character of a cell.
+	LockMaskChar
+	// If set, painting operations do not modify the foreground color of a cell.
+	LockMaskFg
+	// If set, painting operations do not modify the background color of a cell.
+	LockMaskBg
+)
 
 type Cell struct {
 	Value byte
@@ -55,10 +71,24 @@ func (c Cell) IsZero() bool {
 	return c.Value == ' ' && bg == tcell.ColorDefault
 }
 
+// Target that a buffer can be rendered to.
+type Painter interface {
+	SetByte(x, y int, v byte, style tcell.Style)
+}
+
 type Buffer struct {
 	Data            Grid[Cell]
 	activeSelection bool
 	SelectionMask   Grid[bool]
+	// Symmetry that brush strokes, sprays and region fills are repeated with.
+	Symmetry Symmetry
+	// Colors that the ANSI colors of the cells stand for in exported ANSI, HTML and PNG
+	// files. Nil for the ANSI colors themselves.
+	Palette *Palette
+
+	// Region of the buffer that was modified since the last call to TakeDamage.
+	damage    Area
+	isDamaged bool
 }
 
 func MakeBuffer(width, height int) *Buffer {
@@ -83,6 +113,11 @@ func (b *Buffer) Render(screen tcell.Screen, x, y int, overwrite bool) {
 	}
 }
 
+// Returns whether the buffer has an active selection.
+func (b *Buffer) HasSelection() bool {
+	return b.activeSelection
+}
+
 func (b *Buffer) RenderWith(p Painter, x, y int, overwrite bool) {
 	for dy := range b.Data.Height {
 		for dx := range b.Data.Width {
@@ -97,6 +132,36 @@ func (b *Buffer) RenderWith(p Painter, x, y int, overwrite bool) {
 	}
 }
 
+// Marks a rectangular region of the buffer as modified so that it gets repainted.
+func (b *Buffer) Damage(x, y, w, h int) {
+	region, ok := Area{X: x, Y: y, Width: w, Height: h}.Intersection(Area{
+		Width:  b.Data.Width,
+		Height: b.Data.Height,
+	})
+	if !ok || region.Width <= 0 || region.Height <= 0 {
+		return
+	}
+
+	if b.isDamaged {
+		b.damage = b.damage.Union(region)
+	} else {
+		b.damage = region
+		b.isDamaged = true
+	}
+}
+
+// Marks the entire buffer as modified.
+func (b *Buffer) DamageAll() {
+	b.Damage(0, 0, b.Data.Width, b.Data.Height)
+}
+
+// Returns the region of the buffer modified since the last call and resets it.
+func (b *Buffer) TakeDamage() (Area, bool) {
+	damage, isDamaged := b.damage, b.isDamaged
+	b.damage, b.isDamaged = Area{}, false
+	return damage, isDamaged
+}
+
 func (b *Buffer) Get(x, y int) (*Cell, bool) {
 	return b.Data.GetRef(x, y)
 }
@@ -112,23 +177,34 @@ func (b *Buffer) Set(x int, y int, v byte, s tcell.Style) {
 		Value: v,
 		Style: s,
 	})
+	b.Damage(x, y, 1, 1)
 }
 
 func (b *Buffer) SetCell(x int, y int, cell Cell, mask LockMask) {
+	targetCell, ok := b.mergeCell(x, y, cell, mask)
+	if !ok {
+		return
+	}
+	b.Data.Set(x, y, targetCell)
+	b.Damage(x, y, 1, 1)
+}
+
+// Cell that SetCell would leave at the given position, or false if it would not write there.
+func (b *Buffer) mergeCell(x int, y int, cell Cell, mask LockMask) (Cell, bool) {
 	fg, bg, _ := cell.Style.Decompose()
 	if !b.Data.InBounds(x, y) || !b.SelectionMask.InBounds(x, y) {
-		return
+		return Cell{}, false
 	}
 
 	if b.activeSelection && !b.SelectionMask.MustGet(x, y) {
-		return
+		return Cell{}, false
 	}
 
 	targetCell := b.Data.MustGet(x, y)
 	_, targetBg, _ := targetCell.Style.Decompose()
 	if mask&LockMaskAlpha != 0 && (targetCell.Value == ' ' || targetCell.Value == 0) &&
 		targetBg == tcell.ColorDefault {
-		return
+		return Cell{}, false
 	}
 
 	if mask&LockMaskChar == 0 {
@@ -147,7 +223,7 @@ func (b *Buffer) SetCell(x int, y int, cell Cell, mask LockMask) {
 		targetCell.Style = targetCell.Style.Foreground(tcell.ColorDefault)
 	}
 
-	b.Data.Set(x, y, targetCell)
+	return targetCell, true
 }
 
 func (b *Buffer) SetString(x int, y int, s []byte, st tcell.Style) {
@@ -157,6 +233,7 @@ func (b *Buffer) SetString(x int, y int, s []byte, st tcell.Style) {
 			Style: st,
 		})
 	}
+	b.Damage(x, y, len(s), 1)
 }
 
 func (b *Buffer) Import(r io.Reader) error {
@@ -168,6 +245,7 @@ func (b *Buffer) Import(r io.Reader) error {
 	b.Data = MakeGrid(data.Width, data.Height, Cell{})
 	b.SelectionMask = MakeGrid(data.Width, data.Height, false)
 	b.activeSelection = false
+	b.DamageAll()
 
 	for y := range b.Data.Height {
 		for x := range b.Data.Width {
@@ -201,7 +279,7 @@ func (b *Buffer) Load(r io.Reader) error {
 	if err := binary.Read(r, binary.BigEndian, &magic); err != nil {
 		return err
 	}
-	if magic != magicNumber {
+	if magic != MagicNumber {
 		return errors.New("Invalid magic number")
 	}
 
@@ -222,6 +300,7 @@ func (b *Buffer) Load(r io.Reader) error {
 	b.Data = MakeGrid(int(width), int(height), Cell{})
 	b.SelectionMask = MakeGrid(int(width), int(height), false)
 	b.activeSelection = false
+	b.DamageAll()
 
 	for y := range int(height) {
 		for x := range int(width) {
@@ -237,7 +316,7 @@ func (b *Buffer) Load(r io.Reader) error {
 }
 
 func (b *Buffer) Save(w io.Writer) error {
-	if err := binary.Write(w, binary.BigEndian, magicNumber); err != nil {
+	if err := binary.Write(w, binary.BigEndian, MagicNumber); err != nil {
 		return err
 	}
 
@@ -304,6 +383,8 @@ func (b *Buffer) Clone() *Buffer {
 		Data:            b.Data.ShallowClone(),
 		SelectionMask:   b.SelectionMask.ShallowClone(),
 		activeSelection: b.activeSelection,
+		Symmetry:        b.Symmetry,
+		Palette:         b.Palette,
 	}
 }
 
@@ -313,6 +394,7 @@ func (b *Buffer) Clear() {
 			b.Data.Set(x, y, Cell{Value: ' '})
 		}
 	}
+	b.DamageAll()
 }
 
 func (b *Buffer) Deselect() {
@@ -322,6 +404,7 @@ func (b *Buffer) Deselect() {
 			b.SelectionMask.Set(x, y, false)
 		}
 	}
+	b.DamageAll()
 }
 
 func (b *Buffer) Translate(
@@ -387,25 +470,78 @@ func (b *Buffer) FillRegion(x, y, w, h int, cell Cell, mask LockMask) {
 	maxY := max(0, min(b.Data.Height, y+h))
 	for yy := minY; yy < maxY; yy++ {
 		for xx := minX; xx < maxX; xx++ {
-			b.SetCell(xx, yy, cell, mask)
+			b.setCellMirrored(xx, yy, cell, mask)
 		}
 	}
 }
 
-func (b *Buffer) Stamp(clipboard Grid[Cell], px, py int, mask LockMask) {
-	dx, dy := -clipboard.Width/2, -clipboard.Height/2
+// Whether a clipboard cell is left out when stamping transparently: spaces without a
+// background color.
+func isBlankStampCell(c Cell) bool {
+	_, bg, _ := c.Style.Decompose()
+	return (c.Value == ' ' || c.Value == 0) && bg == tcell.ColorDefault
+}
+
+// Paints the clipboard with its top-left corner at the given position, along with its copies
+// under the buffer's symmetry. Unless opaque is set, blank cells of the clipboard are skipped.
+func (b *Buffer) Stamp(clipboard Grid[Cell], topLeft Position, mask LockMask, opaque bool) {
 	for y := range clipboard.Height {
 		for x := range clipboard.Width {
 			stampCell := clipboard.MustGet(x, y)
-			_, stampBg, _ := stampCell.Style.Decompose()
+			if !opaque && isBlankStampCell(stampCell) {
+				continue
+			}
+			if stampCell.Value == 0 {
+				stampCell.Value = ' '
+			}
+			b.setCellMirrored(x+topLeft.X, y+topLeft.Y, stampCell, mask)
+		}
+	}
+}
 
-			if stampCell.Value == ' ' && stampBg == tcell.ColorDefault {
+// Fills the selection, or the whole buffer if nothing is selected, with copies of the
+// clipboard repeated in both directions. One copy has its top-left corner at origin. Unless
+// opaque is set, blank cells of the clipboard are skipped. Like the other fills of the whole
+// selection, this is not repeated by the symmetry.
+func (b *Buffer) TileFill(clipboard Grid[Cell], origin Position, mask LockMask, opaque bool) {
+	if clipboard.Width == 0 || clipboard.Height == 0 {
+		return
+	}
+	for y := range b.Data.Height {
+		for x := range b.Data.Width {
+			cx := ((x-origin.X)%clipboard.Width + clipboard.Width) % clipboard.Width
+			cy := ((y-origin.Y)%clipboard.Height + clipboard.Height) % clipboard.Height
+			stampCell := clipboard.MustGet(cx, cy)
+			if !opaque && isBlankStampCell(stampCell) {
 				continue
 			}
+			if stampCell.Value == 0 {
+				stampCell.Value = ' '
+			}
+			b.SetCell(x, y, stampCell, mask)
+		}
+	}
+}
 
-			b.SetCell(x+px+dx, y+py+dy, stampCell, mask)
+// Smallest area containing the selection, or the whole buffer if nothing is selected.
+func (b *Buffer) SelectionBounds() Area {
+	if !b.HasSelection() {
+		return Area{Width: b.Data.Width, Height: b.Data.Height}
+	}
+	minX, maxX := b.Data.Width-1, 0
+	minY, maxY := b.Data.Height-1, 0
+	for y := range b.Data.Height {
+		for x := range b.Data.Width {
+			if b.SelectionMask.MustGet(x, y) {
+				minX, maxX = min(minX, x), max(maxX, x)
+				minY, maxY = min(minY, y), max(maxY, y)
+			}
 		}
 	}
+	if minX > maxX || minY > maxY {
+		return Area{Width: b.Data.Width, Height: b.Data.Height}
+	}
+	return Area{X: minX, Y: minY, Width: maxX - minX + 1, Height: maxY - minY + 1}
 }
 
 func (b *Buffer) SetSelection(mask Grid[bool], topLeft Position) {
@@ -418,14 +554,18 @@ func (b *Buffer) SetSelection(mask Grid[bool], topLeft Position) {
 		}
 	}
 	b.activeSelection = true
+	b.DamageAll()
 }
 
-func (b *Buffer) BrushStrokes(radius int, cell Cell, points []Position, mask LockMask) {
+// Paints the brush centered on each of the points.
+func (b *Buffer) BrushStrokes(brush Grid[bool], cell Cell, points []Position, mask LockMask) {
 	for _, pt := range points {
-		b.FillRegion(pt.X-radius/2, pt.Y-radius/2, radius, radius, cell, mask)
+		b.PaintBrush(brush, pt, cell, mask)
 	}
 }
 
+// Sets every selected cell. The symmetry is ignored: every selected cell is painted anyway,
+// and copies outside the selection would not be.
 func (b *Buffer) FillSelection(c Cell, mask LockMask) {
 	for y := range b.Data.Height {
 		for x := range b.Data.Width {
@@ -498,3 +638,21 @@ func (b *Buffer) IsBlank() bool {
 	}
 	return true
 }
+
+// Returns a copy of the given region of the buffer, clipped to its bounds.
+func (b *Buffer) Crop(region Area) *Buffer {
+	region, ok := region.Intersection(Area{Width: b.Data.Width, Height: b.Data.Height})
+	if !ok || region.Width <= 0 || region.Height <= 0 {
+		return MakeBuffer(0, 0)
+	}
+
+	res := MakeBuffer(region.Width, region.Height)
+	res.Palette = b.Palette
+	for y := range region.Height {
+		for x := range region.Width {
+			res.Data.Set(x, y, b.Data.MustGet(x+region.X, y+region.Y))
+			res.SelectionMask.Set(x, y, b.SelectionMask.MustGet(x+region.X, y+region.Y))
+		}
+	}
+	return res
+}
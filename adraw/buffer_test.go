@@ -0,0 +1,123 @@
+package adraw
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func styled(v byte, fg, bg tcell.Color) Cell {
+	return Cell{Value: v, Style: tcell.StyleDefault.Foreground(fg).Background(bg)}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	colors := []tcell.Color{tcell.ColorDefault, tcell.ColorBlack, tcell.ColorRed, tcell.ColorWhite}
+	for _, fg := range colors {
+		for _, bg := range colors {
+			c := styled('x', fg, bg)
+			var d Cell
+			Decode(Encode(&c), &d)
+			dfg, dbg, _ := d.Style.Decompose()
+			if d.Value != 'x' || dfg != fg || dbg != bg {
+				t.Errorf("round trip of %v/%v gave %q %v/%v", fg, bg, d.Value, dfg, dbg)
+			}
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	b := MakeBuffer(4, 3)
+	b.Set(1, 1, '#', tcell.StyleDefault.Foreground(tcell.ColorRed))
+	b.SetCell(2, 2, styled('@', tcell.ColorGreen, tcell.ColorNavy), 0)
+
+	var buf bytes.Buffer
+	if err := b.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &Buffer{}
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !b.Equal(loaded) {
+		t.Fatal("loaded buffer differs from saved buffer")
+	}
+}
+
+func TestLoadInvalidMagic(t *testing.T) {
+	if err := (&Buffer{}).Load(bytes.NewReader(make([]byte, 16))); err == nil {
+		t.Fatal("expected an error for an invalid magic number")
+	}
+}
+
+func TestSetCellLockMask(t *testing.T) {
+	b := MakeBuffer(2, 1)
+	b.SetCell(0, 0, styled('a', tcell.ColorRed, tcell.ColorBlue), 0)
+
+	b.SetCell(0, 0, styled('b', tcell.ColorGreen, tcell.ColorYellow), LockMaskChar|LockMaskBg)
+	c := b.Data.MustGet(0, 0)
+	fg, bg, _ := c.Style.Decompose()
+	if c.Value != 'a' || fg != tcell.ColorGreen || bg != tcell.ColorBlue {
+		t.Errorf("char and bg lock: got %q %v/%v", c.Value, fg, bg)
+	}
+
+	// Alpha lock skips empty cells
+	b.SetCell(1, 0, styled('c', tcell.ColorRed, tcell.ColorDefault), LockMaskAlpha)
+	if c := b.Data.MustGet(1, 0); c.Value != ' ' {
+		t.Errorf("alpha lock painted empty cell with %q", c.Value)
+	}
+}
+
+func TestSelectionClipsPainting(t *testing.T) {
+	b := MakeBuffer(3, 3)
+	b.SetSelection(GridFromSlices([]bool{true}), Position{X: 1, Y: 1})
+	b.FillRegion(0, 0, 3, 3, Cell{Value: '#'}, 0)
+
+	for y := range 3 {
+		for x := range 3 {
+			want := byte(' ')
+			if x == 1 && y == 1 {
+				want = '#'
+			}
+			if v := b.Data.MustGet(x, y).Value; v != want {
+				t.Errorf("cell %d %d = %q, want %q", x, y, v, want)
+			}
+		}
+	}
+
+	clip := b.CopySelection()
+	if clip.Width != 1 || clip.Height != 1 || clip.MustGet(0, 0).Value != '#' {
+		t.Errorf("CopySelection returned %dx%d grid", clip.Width, clip.Height)
+	}
+}
+
+func TestDamage(t *testing.T) {
+	b := MakeBuffer(10, 10)
+	if _, ok := b.TakeDamage(); ok {
+		t.Fatal("new buffer is damaged")
+	}
+
+	b.SetCell(2, 3, Cell{Value: '#'}, 0)
+	b.SetCell(5, 1, Cell{Value: '#'}, 0)
+	damage, ok := b.TakeDamage()
+	want := Area{X: 2, Y: 1, Width: 4, Height: 3}
+	if !ok || damage != want {
+		t.Fatalf("damage = %+v, %v; want %+v", damage, ok, want)
+	}
+	if _, ok := b.TakeDamage(); ok {
+		t.Fatal("damage was not reset")
+	}
+}
+
+func TestCrop(t *testing.T) {
+	b := MakeBuffer(4, 4)
+	b.Set(3, 3, 'x', tcell.StyleDefault)
+	c := b.Crop(Area{X: 2, Y: 2, Width: 5, Height: 5})
+	if c.Data.Width != 2 || c.Data.Height != 2 {
+		t.Fatalf("got %dx%d crop, want 2x2", c.Data.Width, c.Data.Height)
+	}
+	if c.Data.MustGet(1, 1).Value != 'x' {
+		t.Fatal("crop lost cell contents")
+	}
+}
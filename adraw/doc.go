@@ -0,0 +1,7 @@
+// Package adraw implements the document model of ascii-draw: grids of cells, drawing
+// buffers with selections and painting operations, and reading and writing drawings in the
+// binary ascii-draw format, plain text, ANSI, HTML and PNG.
+//
+// Buffers can be rendered to a tcell screen with Buffer.Render, or to a terminal as ANSI
+// color codes with Buffer.ExportANSI.
+package adraw
@@ -0,0 +1,221 @@
+package adraw
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Colors used for the terminal default foreground and background when a format needs a
+// concrete color.
+var (
+	exportDefaultFg = color.RGBA{R: 0xc0, G: 0xc0, B: 0xc0, A: 0xff}
+	exportDefaultBg = color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xff}
+)
+
+// Returns the SGR parameters selecting the given 4-bit, 256-color or RGB color, or the
+// terminal default.
+func sgrColor(c tcell.Color, background bool) string {
+	base := 30
+	if background {
+		base = 40
+	}
+	if c == tcell.ColorDefault {
+		return strconv.Itoa(base + 9)
+	}
+	if c.IsRGB() {
+		r, g, b := c.RGB()
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, r, g, b)
+	}
+	idx := int(c - tcell.ColorValid)
+	if idx >= 16 {
+		return fmt.Sprintf("%d;5;%d", base+8, idx)
+	}
+	if idx >= 8 {
+		return strconv.Itoa(base + 60 + idx - 8)
+	}
+	return strconv.Itoa(base + idx)
+}
+
+// Colors that a cell is exported in, as given by the palette of the buffer.
+func (b *Buffer) exportColors(c Cell) (fg, bg tcell.Color) {
+	fg, bg, _ = b.Palette.Style(c.Style).Decompose()
+	return fg, bg
+}
+
+// Number of colors of a terminal with 24-bit color.
+const TrueColor = 1 << 24
+
+// Returns the color closest to c that a terminal showing the given number of colors has.
+func fitColor(c tcell.Color, colors int) tcell.Color {
+	if c == tcell.ColorDefault || colors >= TrueColor {
+		return c
+	}
+	idx := int(c - tcell.ColorValid)
+	if !c.IsRGB() && idx < colors {
+		return c
+	}
+	if !c.IsRGB() && idx < 16 {
+		// Bright colors on a terminal with only 8 colors
+		return c - 8
+	}
+
+	first := 0
+	if colors >= 256 {
+		// The first 16 colors depend on the theme of the terminal
+		first = 16
+	}
+	palette := make([]tcell.Color, 0, min(colors, 256)-first)
+	for i := first; i < min(colors, 256); i++ {
+		palette = append(palette, tcell.PaletteColor(i))
+	}
+	return tcell.FindColor(c, palette)
+}
+
+func rgbColor(c tcell.Color, def color.RGBA) color.RGBA {
+	if c == tcell.ColorDefault {
+		return def
+	}
+	r, g, b := c.RGB()
+	return color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 0xff}
+}
+
+// Writes the buffer as text with ANSI SGR escape codes for its colors. Every line ends with
+// a reset so that the output can be concatenated with other terminal output.
+func (b *Buffer) ExportANSI(w io.Writer) error {
+	return b.ExportANSIColors(w, TrueColor)
+}
+
+// Like ExportANSI, for a terminal that shows the given number of colors. Colors that the
+// terminal lacks are replaced by the closest ones it has.
+func (b *Buffer) ExportANSIColors(w io.Writer, colors int) error {
+	fitted := map[tcell.Color]tcell.Color{}
+	fit := func(c tcell.Color) tcell.Color {
+		f, ok := fitted[c]
+		if !ok {
+			f = fitColor(c, colors)
+			fitted[c] = f
+		}
+		return f
+	}
+
+	bw := bufio.NewWriter(w)
+	for y := range b.Data.Height {
+		var lastFg, lastBg tcell.Color
+		for x := range b.Data.Width {
+			c := b.Data.MustGet(x, y)
+			fg, bg := b.exportColors(c)
+			fg, bg = fit(fg), fit(bg)
+			if x == 0 || fg != lastFg || bg != lastBg {
+				fmt.Fprintf(bw, "\x1b[%s;%sm", sgrColor(fg, false), sgrColor(bg, true))
+				lastFg, lastBg = fg, bg
+			}
+			v := c.Value
+			if v == 0 {
+				v = ' '
+			}
+			bw.WriteByte(v)
+		}
+		bw.WriteString("\x1b[0m\n")
+	}
+	return bw.Flush()
+}
+
+// Writes the buffer as a standalone HTML document containing a single preformatted block.
+func (b *Buffer) ExportHTML(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(
+		bw,
+		"<style>pre { background: #%02x%02x%02x; color: #%02x%02x%02x; }</style>\n",
+		exportDefaultBg.R, exportDefaultBg.G, exportDefaultBg.B,
+		exportDefaultFg.R, exportDefaultFg.G, exportDefaultFg.B,
+	)
+	fmt.Fprintf(bw, "</head>\n<body>\n<pre>")
+	for y := range b.Data.Height {
+		x := 0
+		for x < b.Data.Width {
+			// Group runs of cells with the same colors into a single span
+			fg, bg := b.exportColors(b.Data.MustGet(x, y))
+			end := x
+			var run []byte
+			for end < b.Data.Width {
+				c := b.Data.MustGet(end, y)
+				cfg, cbg := b.exportColors(c)
+				if cfg != fg || cbg != bg {
+					break
+				}
+				v := c.Value
+				if v == 0 {
+					v = ' '
+				}
+				run = append(run, v)
+				end++
+			}
+
+			text := html.EscapeString(string(run))
+			if fg == tcell.ColorDefault && bg == tcell.ColorDefault {
+				bw.WriteString(text)
+			} else {
+				bw.WriteString("<span style=\"")
+				if fg != tcell.ColorDefault {
+					fmt.Fprintf(bw, "color: %s;", fg.CSS())
+				}
+				if bg != tcell.ColorDefault {
+					fmt.Fprintf(bw, "background: %s;", bg.CSS())
+				}
+				fmt.Fprintf(bw, "\">%s</span>", text)
+			}
+			x = end
+		}
+		bw.WriteByte('\n')
+	}
+	fmt.Fprintf(bw, "</pre>\n</body>\n</html>\n")
+	return bw.Flush()
+}
+
+// Renders the buffer to a PNG image using a fixed 7x13 bitmap font.
+func (b *Buffer) ExportPNG(w io.Writer) error {
+	face := basicfont.Face7x13
+	cw, ch := face.Advance, face.Height
+	img := image.NewRGBA(image.Rect(0, 0, b.Data.Width*cw, b.Data.Height*ch))
+
+	d := &font.Drawer{
+		Dst:  img,
+		Face: face,
+	}
+	for y := range b.Data.Height {
+		for x := range b.Data.Width {
+			c := b.Data.MustGet(x, y)
+			fg, bg := b.exportColors(c)
+			cell := image.Rect(x*cw, y*ch, (x+1)*cw, (y+1)*ch)
+			draw.Draw(
+				img,
+				cell,
+				&image.Uniform{C: rgbColor(bg, exportDefaultBg)},
+				image.Point{},
+				draw.Src,
+			)
+
+			if c.Value <= ' ' || c.Value >= 0x7f {
+				continue
+			}
+			d.Src = &image.Uniform{C: rgbColor(fg, exportDefaultFg)}
+			d.Dot = fixed.P(x*cw, y*ch+face.Ascent)
+			d.DrawBytes([]byte{c.Value})
+		}
+	}
+
+	return png.Encode(w, img)
+}
@@ -0,0 +1,42 @@
+package adraw
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestFitColor(t *testing.T) {
+	tests := []struct {
+		c      tcell.Color
+		colors int
+		want   tcell.Color
+	}{
+		{tcell.ColorRed, 8, tcell.ColorMaroon},
+		{tcell.ColorRed, 16, tcell.ColorRed},
+		{tcell.ColorMaroon, 8, tcell.ColorMaroon},
+		{tcell.ColorDefault, 8, tcell.ColorDefault},
+		{tcell.NewRGBColor(255, 0, 0), TrueColor, tcell.NewRGBColor(255, 0, 0)},
+		{tcell.NewRGBColor(255, 0, 0), 256, tcell.PaletteColor(196)},
+		{tcell.NewRGBColor(0, 0, 250), 16, tcell.ColorBlue},
+		{tcell.PaletteColor(196), 16, tcell.ColorRed},
+	}
+	for _, tt := range tests {
+		if got := fitColor(tt.c, tt.colors); got != tt.want {
+			t.Errorf("fitColor(%v, %d) = %v, want %v", tt.c, tt.colors, got, tt.want)
+		}
+	}
+}
+
+func TestExportANSIColors(t *testing.T) {
+	b := MakeBuffer(2, 1)
+	b.SetCell(0, 0, Cell{Value: 'x', Style: tcell.StyleDefault.Foreground(tcell.ColorRed)}, 0)
+	var sb strings.Builder
+	if err := b.ExportANSIColors(&sb, 8); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sb.String(), "\x1b[31;49mx\x1b[39;49m \x1b[0m\n"; got != want {
+		t.Errorf("export is %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package adraw
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Version of the binary ascii-draw format written by Buffer.Save. The format does not store
+// a version number of its own, so every file with a valid magic number is this version.
+const FormatVersion = 1
+
+// A file format that drawings can be read from or written to.
+type Format string
+
+const (
+	// Binary ascii-draw format, which preserves colors.
+	FormatBinary Format = "adraw"
+	// Plain text, without colors.
+	FormatText Format = "txt"
+	// Text with ANSI SGR color codes. Write only.
+	FormatANSI Format = "ans"
+	// Standalone HTML document. Write only.
+	FormatHTML Format = "html"
+	// PNG image rendered with a bitmap font. Write only.
+	FormatPNG Format = "png"
+)
+
+// Infers a format from the extension of a file name.
+func FormatFromPath(s string) Format {
+	return Format(strings.ToLower(strings.TrimPrefix(filepath.Ext(s), ".")))
+}
+
+// Reads a drawing in either the binary or the plain text format, depending on whether the
+// data starts with the magic number.
+func Read(r io.Reader) (*Buffer, Format, error) {
+	br := bufio.NewReader(r)
+	b := &Buffer{}
+
+	header, _ := br.Peek(8)
+	var magic [8]byte
+	for i := range magic {
+		magic[i] = byte(MagicNumber >> (8 * (7 - i)))
+	}
+	if bytes.Equal(header, magic[:]) {
+		if err := b.Load(br); err != nil {
+			return nil, FormatBinary, err
+		}
+		return b, FormatBinary, nil
+	}
+
+	if err := b.Import(br); err != nil {
+		return nil, FormatText, err
+	}
+	return b, FormatText, nil
+}
+
+// Reads a drawing from a file, see Read.
+func ReadFile(s string) (*Buffer, Format, error) {
+	f, err := os.Open(s)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	b, format, err := Read(f)
+	if err != nil {
+		return nil, format, fmt.Errorf("%s: %w", s, err)
+	}
+	return b, format, nil
+}
+
+// Writes the drawing in the given format.
+func (b *Buffer) Write(w io.Writer, f Format) error {
+	switch f {
+	case FormatBinary:
+		return b.Save(w)
+	case FormatText:
+		return b.Export(w)
+	case FormatANSI:
+		return b.ExportANSI(w)
+	case FormatHTML:
+		return b.ExportHTML(w)
+	case FormatPNG:
+		return b.ExportPNG(w)
+	default:
+		return errUnsupportedFormat(f)
+	}
+}
+
+// Reports whether drawings can be written in the format.
+func (f Format) Writable() bool {
+	switch f {
+	case FormatBinary, FormatText, FormatANSI, FormatHTML, FormatPNG:
+		return true
+	}
+	return false
+}
+
+func errUnsupportedFormat(f Format) error {
+	return fmt.Errorf("unsupported output format %q (want adraw, txt, ans, html or png)", f)
+}
+
+// Writes the drawing to a file in the given format.
+func (b *Buffer) WriteFile(s string, f Format) error {
+	if !f.Writable() {
+		return errUnsupportedFormat(f)
+	}
+	file, err := os.Create(s)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return b.Write(file, f)
+}
@@ -0,0 +1,62 @@
+package adraw
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestReadDetectsFormat(t *testing.T) {
+	b := MakeBuffer(3, 1)
+	b.Set(0, 0, 'a', tcell.StyleDefault.Foreground(tcell.ColorRed))
+
+	var bin bytes.Buffer
+	if err := b.Write(&bin, FormatBinary); err != nil {
+		t.Fatal(err)
+	}
+	if _, f, err := Read(&bin); err != nil || f != FormatBinary {
+		t.Fatalf("Read(binary) = %v, %v", f, err)
+	}
+
+	got, f, err := Read(strings.NewReader("abc\nd"))
+	if err != nil || f != FormatText {
+		t.Fatalf("Read(text) = %v, %v", f, err)
+	}
+	if got.Data.Width != 3 || got.Data.Height != 2 {
+		t.Fatalf("got %dx%d buffer, want 3x2", got.Data.Width, got.Data.Height)
+	}
+}
+
+func TestExportANSI(t *testing.T) {
+	b := MakeBuffer(3, 1)
+	b.Set(0, 0, 'a', tcell.StyleDefault.Foreground(tcell.ColorRed))
+	b.Set(1, 0, 'b', tcell.StyleDefault.Foreground(tcell.ColorRed))
+
+	var out bytes.Buffer
+	if err := b.Write(&out, FormatANSI); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b[91;49mab\x1b[39;49m \x1b[0m\n"
+	if out.String() != want {
+		t.Fatalf("ExportANSI = %q, want %q", out.String(), want)
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	cases := map[string]Format{
+		"a.adraw":      FormatBinary,
+		"dir/b.TXT":    FormatText,
+		"c.html":       FormatHTML,
+		"no-extension": "",
+	}
+	for path, want := range cases {
+		if got := FormatFromPath(path); got != want {
+			t.Errorf("FormatFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+	if Format("bmp").Writable() {
+		t.Error("bmp should not be writable")
+	}
+}
@@ -0,0 +1,75 @@
+package adraw
+
+import "github.com/gdamore/tcell/v2"
+
+// 4x4 ordered dithering thresholds, out of 16.
+var bayer4 = [4][4]int{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// Step of a ramp with the given number of steps for a position t between 0 and 1. Without
+// dithering this is the nearest step. With dithering, cells between two steps pick one of
+// them in an ordered pattern, so that the share of the higher step follows t.
+func RampStep(t float64, steps, x, y int, dither bool) int {
+	if steps <= 1 {
+		return 0
+	}
+	t = max(0, min(1, t))
+	v := t * float64(steps-1)
+	i := int(v)
+	if i >= steps-1 {
+		return steps - 1
+	}
+	if !dither {
+		if v-float64(i) >= 0.5 {
+			i++
+		}
+		return i
+	}
+	threshold := (float64(bayer4[y&3][x&3]) + 0.5) / 16
+	if v-float64(i) > threshold {
+		i++
+	}
+	return i
+}
+
+// Fills the selection, or the whole buffer if nothing is selected, with a gradient running
+// from one position to another. Cells are projected onto the line between the positions:
+// cells at or before from get the first step of the ramps and cells at or after to get the
+// last. An empty ramp leaves that part of the cells alone, as far as mask allows. The
+// symmetry is ignored, as for other fills of the whole selection.
+func (b *Buffer) Gradient(
+	from, to Position,
+	chars []byte,
+	colors []tcell.Color,
+	dither bool,
+	mask LockMask,
+) {
+	if len(chars) == 0 {
+		mask |= LockMaskChar
+	}
+	if len(colors) == 0 {
+		mask |= LockMaskFg
+	}
+	dx, dy := float64(to.X-from.X), float64(to.Y-from.Y)
+	length := dx*dx + dy*dy
+	for y := range b.Data.Height {
+		for x := range b.Data.Width {
+			var t float64
+			if length > 0 {
+				t = (float64(x-from.X)*dx + float64(y-from.Y)*dy) / length
+			}
+			cell := b.Data.MustGet(x, y)
+			if len(chars) > 0 {
+				cell.Value = chars[RampStep(t, len(chars), x, y, dither)]
+			}
+			if len(colors) > 0 {
+				cell.Style = cell.Style.Foreground(colors[RampStep(t, len(colors), x, y, dither)])
+			}
+			b.SetCell(x, y, cell, mask)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package adraw
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestRampStep(t *testing.T) {
+	if got := RampStep(0.4, 3, 0, 0, false); got != 1 {
+		t.Errorf("step at 0.4 of 3 is %d, want 1", got)
+	}
+	if got := RampStep(-1, 3, 0, 0, true); got != 0 {
+		t.Errorf("step before the start is %d, want 0", got)
+	}
+	if got := RampStep(2, 3, 0, 0, true); got != 2 {
+		t.Errorf("step after the end is %d, want 2", got)
+	}
+
+	// Halfway between two steps, half of the cells take each of them
+	var upper int
+	for y := range 4 {
+		for x := range 4 {
+			upper += RampStep(0.5, 2, x, y, true)
+		}
+	}
+	if upper != 8 {
+		t.Errorf("%d of 16 dithered cells took the upper step, want 8", upper)
+	}
+}
+
+func TestGradient(t *testing.T) {
+	b := MakeBuffer(6, 2)
+	b.Set(0, 1, 'x', tcell.StyleDefault)
+	b.Gradient(Position{X: 1, Y: 0}, Position{X: 4, Y: 0}, []byte(".:#"), nil, false, 0)
+
+	for y, want := range []string{"..::##", "..::##"} {
+		var got []byte
+		for x := range 6 {
+			got = append(got, b.Data.MustGet(x, y).Value)
+		}
+		if string(got) != want {
+			t.Errorf("row %d is %q, want %q", y, got, want)
+		}
+	}
+
+	colors := []tcell.Color{tcell.ColorRed, tcell.ColorBlue}
+	b.Gradient(Position{}, Position{X: 5}, nil, colors, false, 0)
+	c := b.Data.MustGet(5, 0)
+	if fg, _, _ := c.Style.Decompose(); c.Value != '#' || fg != tcell.ColorBlue {
+		t.Errorf("color gradient gave %q in %v, want '#' in blue", c.Value, fg)
+	}
+}
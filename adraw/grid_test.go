@@ -0,0 +1,54 @@
+package adraw
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGridGetSet(t *testing.T) {
+	g := MakeGrid(3, 2, 0)
+	if !g.Set(2, 1, 5) {
+		t.Fatal("Set in bounds returned false")
+	}
+	if g.Set(3, 0, 1) || g.Set(0, -1, 1) {
+		t.Fatal("Set out of bounds returned true")
+	}
+	if v, ok := g.Get(2, 1); !ok || v != 5 {
+		t.Fatalf("Get(2, 1) = %d, %v; want 5, true", v, ok)
+	}
+	if _, ok := g.Get(-1, 0); ok {
+		t.Fatal("Get out of bounds returned true")
+	}
+}
+
+func TestGridResize(t *testing.T) {
+	g := GridFromSlices([]int{1, 2}, []int{3, 4})
+	r := g.Resize(1, 1, 3, 3, 0)
+	want := GridFromSlices([]int{0, 0, 0}, []int{0, 1, 2}, []int{0, 3, 4})
+	for y := range want.Height {
+		for x := range want.Width {
+			if r.MustGet(x, y) != want.MustGet(x, y) {
+				t.Fatalf("Resize mismatch at %d %d: got %d want %d",
+					x, y, r.MustGet(x, y), want.MustGet(x, y))
+			}
+		}
+	}
+}
+
+func TestGridFromReader(t *testing.T) {
+	g, err := GridFromReader(strings.NewReader("ab\n\tc\né"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Width != 5 || g.Height != 3 {
+		t.Fatalf("got %dx%d grid, want 5x3", g.Width, g.Height)
+	}
+	rows := []string{"ab   ", "    c", "?    "}
+	for y, row := range rows {
+		for x := range row {
+			if g.MustGet(x, y) != row[x] {
+				t.Errorf("cell %d %d = %q, want %q", x, y, g.MustGet(x, y), row[x])
+			}
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package adraw
 
 func linePositionsLow(ax, ay, bx, by int) (pos []Position) {
 	dx, dy := bx-ax, by-ay
@@ -0,0 +1,236 @@
+package adraw
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Number of colors that cells can refer to. Cells store the index of one of them, as one of
+// the 16 ANSI colors, or the terminal default.
+const PaletteSize = 16
+
+type PaletteEntry struct {
+	Name  string
+	Color tcell.Color
+}
+
+// Colors that the color indices of cells stand for. Index i is stored in cells as the ANSI
+// color tcell.ColorValid+i, so changing the palette recolors every drawing that uses it.
+// Indices past the end of the palette keep their ANSI color.
+type Palette struct {
+	Name    string
+	Entries []PaletteEntry
+}
+
+// Palette of the 16 ANSI colors, shown in whatever colors the terminal gives them.
+func DefaultPalette() *Palette {
+	p := &Palette{Name: "ANSI"}
+	for i := range PaletteSize {
+		c := tcell.ColorValid + tcell.Color(i)
+		p.Entries = append(p.Entries, PaletteEntry{Name: c.Name(), Color: c})
+	}
+	return p
+}
+
+func (p *Palette) Clone() *Palette {
+	return &Palette{Name: p.Name, Entries: append([]PaletteEntry(nil), p.Entries...)}
+}
+
+// Entry for the color index i. Indices past the end of the palette give their ANSI color.
+func (p *Palette) Entry(i int) PaletteEntry {
+	if i < len(p.Entries) {
+		return p.Entries[i]
+	}
+	c := tcell.ColorValid + tcell.Color(i)
+	return PaletteEntry{Name: c.Name(), Color: c}
+}
+
+// Color shown for a color stored in a cell. A nil palette shows the ANSI colors.
+func (p *Palette) Color(c tcell.Color) tcell.Color {
+	if p == nil {
+		return c
+	}
+	i := int(c - tcell.ColorValid)
+	if c < tcell.ColorValid || i >= len(p.Entries) {
+		return c
+	}
+	return p.Entries[i].Color
+}
+
+// Style shown for the style of a cell.
+func (p *Palette) Style(st tcell.Style) tcell.Style {
+	fg, bg, _ := st.Decompose()
+	return st.Foreground(p.Color(fg)).Background(p.Color(bg))
+}
+
+// Color stored in cells for the entry with the given name, ignoring case.
+func (p *Palette) Lookup(name string) (tcell.Color, bool) {
+	for i, e := range p.Entries {
+		if strings.EqualFold(e.Name, name) {
+			return tcell.ColorValid + tcell.Color(i), true
+		}
+	}
+	return tcell.ColorDefault, false
+}
+
+func (p *Palette) validate() error {
+	if len(p.Entries) == 0 {
+		return errors.New("palette has no colors")
+	}
+	if len(p.Entries) > PaletteSize {
+		p.Entries = p.Entries[:PaletteSize]
+	}
+	return nil
+}
+
+const gplHeader = "GIMP Palette"
+
+// Reads a palette in the GIMP .gpl format. Only the first PaletteSize colors are kept.
+func ReadGPL(r io.Reader) (*Palette, error) {
+	sc := bufio.NewScanner(r)
+	if !sc.Scan() || strings.TrimSpace(sc.Text()) != gplHeader {
+		return nil, errors.New("not a GIMP palette")
+	}
+
+	p := &Palette{}
+	for line := 2; sc.Scan(); line++ {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || strings.HasPrefix(s, "#") || strings.HasPrefix(s, "Columns:") {
+			continue
+		}
+		if name, ok := strings.CutPrefix(s, "Name:"); ok {
+			p.Name = strings.TrimSpace(name)
+			continue
+		}
+
+		fields := strings.Fields(s)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: want red, green and blue values", line)
+		}
+		var rgb [3]int32
+		for i := range rgb {
+			v, err := strconv.Atoi(fields[i])
+			if err != nil || v < 0 || v > 255 {
+				return nil, fmt.Errorf("line %d: invalid color value %q", line, fields[i])
+			}
+			rgb[i] = int32(v)
+		}
+		p.Entries = append(p.Entries, PaletteEntry{
+			Name:  strings.Join(fields[3:], " "),
+			Color: tcell.NewRGBColor(rgb[0], rgb[1], rgb[2]),
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Writes the palette in the GIMP .gpl format.
+func (p *Palette) WriteGPL(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\nName: %s\nColumns: 8\n#\n", gplHeader, p.Name)
+	for _, e := range p.Entries {
+		r, g, b := e.Color.RGB()
+		fmt.Fprintf(bw, "%3d %3d %3d\t%s\n", r, g, b, e.Name)
+	}
+	return bw.Flush()
+}
+
+type jsonPaletteEntry struct {
+	Name string `json:"name"`
+	// A color name or #rrggbb. The 16 ANSI color names stand for the colors of the terminal.
+	Color string `json:"color"`
+}
+
+type jsonPalette struct {
+	Name   string             `json:"name"`
+	Colors []jsonPaletteEntry `json:"colors"`
+}
+
+// Reads a palette in the JSON format written by WriteJSON. Only the first PaletteSize colors
+// are kept.
+func ReadPaletteJSON(r io.Reader) (*Palette, error) {
+	var jp jsonPalette
+	if err := json.NewDecoder(r).Decode(&jp); err != nil {
+		return nil, err
+	}
+	p := &Palette{Name: jp.Name}
+	for _, e := range jp.Colors {
+		c := tcell.GetColor(e.Color)
+		if c == tcell.ColorDefault {
+			return nil, fmt.Errorf("invalid color %q", e.Color)
+		}
+		p.Entries = append(p.Entries, PaletteEntry{Name: e.Name, Color: c})
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Writes the palette as JSON: an object with a name and a list of colors, each with a name
+// and a color.
+func (p *Palette) WriteJSON(w io.Writer) error {
+	jp := jsonPalette{Name: p.Name, Colors: []jsonPaletteEntry{}}
+	for _, e := range p.Entries {
+		jp.Colors = append(jp.Colors, jsonPaletteEntry{Name: e.Name, Color: e.Color.Name(true)})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jp)
+}
+
+func isGPLPath(s string) bool {
+	return strings.EqualFold(filepath.Ext(s), ".gpl")
+}
+
+// Reads a palette from a file, in the GIMP format if it has the .gpl extension and as JSON
+// otherwise.
+func ReadPaletteFile(s string) (*Palette, error) {
+	f, err := os.Open(s)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	read := ReadPaletteJSON
+	if isGPLPath(s) {
+		read = ReadGPL
+	}
+	p, err := read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s, err)
+	}
+	return p, nil
+}
+
+// Writes the palette to a file, in the GIMP format if it has the .gpl extension and as JSON
+// otherwise.
+func (p *Palette) WriteFile(s string) error {
+	f, err := os.Create(s)
+	if err != nil {
+		return err
+	}
+	write := p.WriteJSON
+	if isGPLPath(s) {
+		write = p.WriteGPL
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,119 @@
+package adraw
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const testGPL = `GIMP Palette
+Name: Forest
+Columns: 2
+# leaves and bark
+ 34 139  34	leaf green
+139  69  19	bark
+`
+
+func TestReadGPL(t *testing.T) {
+	p, err := ReadGPL(strings.NewReader(testGPL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Forest" || len(p.Entries) != 2 {
+		t.Fatalf("read %q with %d entries, want Forest with 2", p.Name, len(p.Entries))
+	}
+	if e := p.Entries[0]; e.Name != "leaf green" || e.Color != tcell.NewRGBColor(34, 139, 34) {
+		t.Errorf("first entry is %+v", e)
+	}
+
+	if _, err := ReadGPL(strings.NewReader("GIMP Palette\n1 2\n")); err == nil {
+		t.Error("read a color without a blue value")
+	}
+	if _, err := ReadGPL(strings.NewReader(`{"name": "x"}`)); err == nil {
+		t.Error("read JSON as a GIMP palette")
+	}
+}
+
+func TestPaletteRoundTrip(t *testing.T) {
+	p, _ := ReadGPL(strings.NewReader(testGPL))
+	p.Entries = append(p.Entries, PaletteEntry{Name: "sky", Color: tcell.ColorNavy})
+
+	var buf bytes.Buffer
+	if err := p.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadPaletteJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != p.Name || len(got.Entries) != len(p.Entries) {
+		t.Fatalf("read back %+v, want %+v", got, p)
+	}
+	for i := range p.Entries {
+		if got.Entries[i] != p.Entries[i] {
+			t.Errorf("entry %d is %+v, want %+v", i, got.Entries[i], p.Entries[i])
+		}
+	}
+
+	buf.Reset()
+	if err := p.WriteGPL(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadGPL(&buf); err != nil || got.Entries[1] != p.Entries[1] {
+		t.Errorf("read back %+v, %v from GIMP palette", got, err)
+	}
+}
+
+func TestPaletteColors(t *testing.T) {
+	p := &Palette{Entries: []PaletteEntry{{Name: "ink", Color: tcell.NewRGBColor(1, 2, 3)}}}
+	if got := p.Color(tcell.ColorBlack); got != tcell.NewRGBColor(1, 2, 3) {
+		t.Errorf("index 0 is shown as %v", got)
+	}
+	// Indices past the end of the palette and the default color are left alone
+	for _, c := range []tcell.Color{tcell.ColorRed, tcell.ColorDefault} {
+		if got := p.Color(c); got != c {
+			t.Errorf("%v is shown as %v", c, got)
+		}
+	}
+	if c, ok := p.Lookup("INK"); !ok || c != tcell.ColorBlack {
+		t.Errorf("looked up %v, %v for ink", c, ok)
+	}
+
+	long := &Palette{}
+	for range PaletteSize + 4 {
+		long.Entries = append(long.Entries, PaletteEntry{Color: tcell.ColorRed})
+	}
+	if err := long.validate(); err != nil || len(long.Entries) != PaletteSize {
+		t.Errorf("kept %d entries, %v", len(long.Entries), err)
+	}
+}
+
+func TestExportWithPalette(t *testing.T) {
+	b := MakeBuffer(1, 1)
+	b.Set(0, 0, 'x', tcell.StyleDefault.Foreground(tcell.ColorBlack))
+	b.Palette = &Palette{Entries: []PaletteEntry{{Color: tcell.NewRGBColor(1, 2, 3)}}}
+
+	var buf bytes.Buffer
+	if err := b.ExportANSI(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\x1b[38;2;1;2;3;49mx"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("exported %q, want it to start with %q", buf.String(), want)
+	}
+}
+
+func TestExportWithPaletteIn256Colors(t *testing.T) {
+	b := MakeBuffer(1, 1)
+	b.Set(0, 0, 'x', tcell.StyleDefault.Foreground(tcell.ColorBlack))
+	b.Palette = &Palette{Entries: []PaletteEntry{{Color: tcell.NewRGBColor(0xff, 0x87, 0x00)}}}
+
+	var buf bytes.Buffer
+	if err := b.ExportANSIColors(&buf, 256); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\x1b[38;5;208;49mx"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("exported %q, want it to start with %q", buf.String(), want)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package adraw
 
 type Position struct {
 	X int
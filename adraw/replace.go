@@ -0,0 +1,74 @@
+package adraw
+
+// Cells that find and replace looks for. Only the parts of the cell in Parts are compared:
+// the rest match anything.
+type CellPattern struct {
+	Cell  Cell
+	Parts LockMask
+}
+
+func (p CellPattern) Matches(c Cell) bool {
+	fg, bg, _ := c.Style.Decompose()
+	wantFg, wantBg, _ := p.Cell.Style.Decompose()
+	if p.Parts&LockMaskChar != 0 && c.Value != p.Cell.Value {
+		return false
+	}
+	if p.Parts&LockMaskFg != 0 && fg != wantFg {
+		return false
+	}
+	if p.Parts&LockMaskBg != 0 && bg != wantBg {
+		return false
+	}
+	return true
+}
+
+// Cells to search: the selection if there is one, or else the whole buffer.
+func (b *Buffer) searched(x, y int) bool {
+	return !b.activeSelection || b.SelectionMask.MustGet(x, y)
+}
+
+// Number of cells in the selection, or in the whole buffer, that match the pattern.
+func (b *Buffer) CountMatches(p CellPattern) int {
+	var n int
+	for y := range b.Data.Height {
+		for x := range b.Data.Width {
+			if b.searched(x, y) && p.Matches(b.Data.MustGet(x, y)) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Writes the parts of with that are not in keep to every cell in the selection, or in the
+// whole buffer, that matches the pattern. Returns the number of cells that changed.
+func (b *Buffer) Replace(p CellPattern, with Cell, keep LockMask) int {
+	return b.replace(p, with, keep, true)
+}
+
+// Number of cells that Replace would change, without changing them.
+func (b *Buffer) CountReplacements(p CellPattern, with Cell, keep LockMask) int {
+	return b.replace(p, with, keep, false)
+}
+
+func (b *Buffer) replace(p CellPattern, with Cell, keep LockMask, write bool) int {
+	var n int
+	for y := range b.Data.Height {
+		for x := range b.Data.Width {
+			old := b.Data.MustGet(x, y)
+			if !b.searched(x, y) || !p.Matches(old) {
+				continue
+			}
+			cell, ok := b.mergeCell(x, y, with, keep)
+			if !ok || cell == old {
+				continue
+			}
+			if write {
+				b.Data.Set(x, y, cell)
+				b.Damage(x, y, 1, 1)
+			}
+			n++
+		}
+	}
+	return n
+}
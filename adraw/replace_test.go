@@ -0,0 +1,82 @@
+package adraw
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestCellPattern(t *testing.T) {
+	red := styled('#', tcell.ColorRed, tcell.ColorDefault)
+	tests := []struct {
+		pattern CellPattern
+		want    bool
+	}{
+		{CellPattern{}, true},
+		{CellPattern{Cell: Cell{Value: '#'}, Parts: LockMaskChar}, true},
+		{CellPattern{Cell: Cell{Value: '*'}, Parts: LockMaskChar}, false},
+		{CellPattern{Cell: styled('*', tcell.ColorRed, tcell.ColorBlue), Parts: LockMaskFg}, true},
+		{CellPattern{Cell: styled('#', tcell.ColorRed, tcell.ColorBlue), Parts: LockMaskChar | LockMaskBg}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.pattern.Matches(red); got != tt.want {
+			t.Errorf("%+v matches %+v: got %v, want %v", tt.pattern, red, got, tt.want)
+		}
+	}
+}
+
+func TestReplace(t *testing.T) {
+	b := MakeBuffer(4, 2)
+	b.SetString(0, 0, []byte("#*#*"), tcell.StyleDefault)
+	b.SetString(0, 1, []byte("*#*#"), tcell.StyleDefault)
+
+	find := CellPattern{Cell: Cell{Value: '*'}, Parts: LockMaskChar}
+	if n := b.CountMatches(find); n != 4 {
+		t.Errorf("found %d cells, want 4", n)
+	}
+
+	// Only the selection is searched, and only the foreground is written
+	mask := MakeGrid(4, 2, false)
+	mask.Set(0, 1, true)
+	mask.Set(1, 1, true)
+	b.SetSelection(mask, Position{})
+	green := styled('+', tcell.ColorGreen, tcell.ColorDefault)
+	if n := b.Replace(find, green, LockMaskChar|LockMaskBg); n != 1 {
+		t.Errorf("replaced %d cells, want 1", n)
+	}
+	c := b.Data.MustGet(0, 1)
+	if fg, _, _ := c.Style.Decompose(); c.Value != '*' || fg != tcell.ColorGreen {
+		t.Errorf("replaced cell is %q in %v, want '*' in green", c.Value, fg)
+	}
+	if fg, _, _ := b.Data.MustGet(1, 0).Style.Decompose(); fg == tcell.ColorGreen {
+		t.Errorf("cell outside the selection was replaced")
+	}
+}
+
+func TestReplaceCountsChanges(t *testing.T) {
+	b := MakeBuffer(3, 1)
+	b.SetString(0, 0, []byte("#  "), tcell.StyleDefault)
+
+	// Replacing a character with itself changes nothing
+	same := CellPattern{Cell: Cell{Value: '#'}, Parts: LockMaskChar}
+	if n := b.Replace(same, Cell{Value: '#'}, LockMaskFg|LockMaskBg); n != 0 {
+		t.Errorf("replacing # with # changed %d cells, want 0", n)
+	}
+
+	// Blanks are skipped under the alpha lock, so only one of the matches changes
+	all := CellPattern{}
+	with := Cell{Value: '+'}
+	keep := LockMaskFg | LockMaskBg | LockMaskAlpha
+	if n := b.CountReplacements(all, with, keep); n != 1 {
+		t.Errorf("counted %d replacements, want 1", n)
+	}
+	if b.Data.MustGet(0, 0).Value != '#' {
+		t.Errorf("counting replacements changed the buffer")
+	}
+	if n := b.Replace(all, with, keep); n != 1 {
+		t.Errorf("replaced %d cells, want 1", n)
+	}
+	if got := b.Data.MustGet(0, 0).Value; got != '+' {
+		t.Errorf("replaced cell is %q, want '+'", got)
+	}
+}
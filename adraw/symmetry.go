@@ -0,0 +1,201 @@
+package adraw
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Height of a terminal cell in units of its width, used to keep radial symmetry round on
+// screen.
+const CellAspect = 2
+
+type SymmetryMode int
+
+const (
+	SymmetryOff SymmetryMode = iota
+	// Mirrored left to right, across a vertical axis through the center.
+	SymmetryHorizontal
+	// Mirrored top to bottom, across a horizontal axis through the center.
+	SymmetryVertical
+	// Mirrored across both axes.
+	SymmetryBoth
+	// Rotated around the center in equal steps.
+	SymmetryRadial
+)
+
+var symmetryModeNames = [...]string{"off", "horizontal", "vertical", "both", "radial"}
+
+func (s SymmetryMode) String() string {
+	return symmetryModeNames[s]
+}
+
+func ParseSymmetryMode(s string) (SymmetryMode, error) {
+	idx := slices.Index(symmetryModeNames[:], s)
+	if idx == -1 {
+		return SymmetryOff, fmt.Errorf("invalid symmetry %q (want off, horizontal, vertical, both or radial)", s)
+	}
+	return SymmetryMode(idx), nil
+}
+
+// Symmetry that painting is repeated with. The center lies on a cell or halfway between two
+// cells.
+type Symmetry struct {
+	Mode             SymmetryMode
+	CenterX, CenterY float64
+	// Number of copies around the center for radial symmetry, including the original.
+	Ways int
+}
+
+// Characters that change into each other when mirrored or turned.
+var (
+	mirrorHChars = charSwaps("/\\", "()", "[]", "{}", "<>", "bd", "pq")
+	mirrorVChars = charSwaps("/\\", "^v", "bp", "dq")
+	turnChars    = charSwaps("-|", "/\\")
+	// Half turn, the same as mirroring across both axes
+	halfTurnChars = charSwaps("^v", "<>", "()", "[]", "{}", "bq", "dp")
+	// Quarter turn clockwise, and the other way
+	turnCWChars  = charCycle(turnChars, ">v<^")
+	turnCCWChars = charCycle(turnChars, "^<v>")
+)
+
+func charSwaps(pairs ...string) [128]byte {
+	var m [128]byte
+	for i := range m {
+		m[i] = byte(i)
+	}
+	for _, p := range pairs {
+		m[p[0]], m[p[1]] = p[1], p[0]
+	}
+	return m
+}
+
+func charCycle(m [128]byte, cycle string) [128]byte {
+	for i := range cycle {
+		m[cycle[i]] = cycle[(i+1)%len(cycle)]
+	}
+	return m
+}
+
+// Maps a position and its character to one of the copies made by a symmetry.
+type SymmetryTransform struct {
+	pos func(p Position) Position
+	// For rotations, maps a point in cells to its copy. Rotations stretch cells over several
+	// cells, so they map points rather than positions.
+	point func(x, y float64) (float64, float64)
+	chars *[128]byte
+}
+
+// Offsets of the points that a rotated cell is copied from. Neighboring points are half a
+// cell width apart, also across the edges of cells, so that their copies land in the same or
+// neighboring cells and the copies of neighboring cells leave no holes between them.
+var (
+	cellPointsX = [...]float64{-0.25, 0.25}
+	cellPointsY = [...]float64{-0.375, -0.125, 0.125, 0.375}
+)
+
+// Positions that the cell at p is copied to.
+func (t SymmetryTransform) Positions(p Position) []Position {
+	switch {
+	case t.point != nil:
+		var res []Position
+		for _, oy := range cellPointsY {
+			for _, ox := range cellPointsX {
+				x, y := t.point(float64(p.X)+ox, float64(p.Y)+oy)
+				q := Position{X: int(math.Round(x)), Y: int(math.Round(y))}
+				if !slices.Contains(res, q) {
+					res = append(res, q)
+				}
+			}
+		}
+		return res
+	case t.pos != nil:
+		return []Position{t.pos(p)}
+	default:
+		return []Position{p}
+	}
+}
+
+func (t SymmetryTransform) Char(v byte) byte {
+	if t.chars == nil || v >= 128 {
+		return v
+	}
+	return t.chars[v]
+}
+
+// Transforms to every copy of the symmetry, starting with the original.
+func (s Symmetry) Transforms() []SymmetryTransform {
+	flipX := func(p Position) Position {
+		return Position{X: int(math.Round(2*s.CenterX)) - p.X, Y: p.Y}
+	}
+	flipY := func(p Position) Position {
+		return Position{X: p.X, Y: int(math.Round(2*s.CenterY)) - p.Y}
+	}
+
+	res := []SymmetryTransform{{}}
+	switch s.Mode {
+	case SymmetryHorizontal:
+		res = append(res, SymmetryTransform{pos: flipX, chars: &mirrorHChars})
+	case SymmetryVertical:
+		res = append(res, SymmetryTransform{pos: flipY, chars: &mirrorVChars})
+	case SymmetryBoth:
+		res = append(res,
+			SymmetryTransform{pos: flipX, chars: &mirrorHChars},
+			SymmetryTransform{pos: flipY, chars: &mirrorVChars},
+			SymmetryTransform{
+				pos:   func(p Position) Position { return flipX(flipY(p)) },
+				chars: &halfTurnChars,
+			},
+		)
+	case SymmetryRadial:
+		for k := 1; k < s.Ways; k++ {
+			res = append(res, s.rotation(k))
+		}
+	}
+	return res
+}
+
+// The k-th of the radial copies. Characters are only turned for quarter and half turns.
+func (s Symmetry) rotation(k int) SymmetryTransform {
+	angle := 2 * math.Pi * float64(k) / float64(s.Ways)
+	sin, cos := math.Sincos(angle)
+	t := SymmetryTransform{
+		point: func(x, y float64) (float64, float64) {
+			dx, dy := x-s.CenterX, (y-s.CenterY)*CellAspect
+			return s.CenterX + dx*cos - dy*sin, s.CenterY + (dx*sin+dy*cos)/CellAspect
+		},
+	}
+	if (4*k)%s.Ways == 0 {
+		switch (4 * k / s.Ways) % 4 {
+		case 1:
+			t.chars = &turnCWChars
+		case 2:
+			t.chars = &halfTurnChars
+		case 3:
+			t.chars = &turnCCWChars
+		}
+	}
+	return t
+}
+
+// Sets a cell and its copies under the buffer's symmetry. A copy that lands on the cell
+// itself or on an earlier copy is skipped, so cells on an axis keep the original character.
+// Rotated copies of a cell can take up several cells.
+func (b *Buffer) setCellMirrored(x, y int, cell Cell, mask LockMask) {
+	if b.Symmetry.Mode == SymmetryOff {
+		b.SetCell(x, y, cell, mask)
+		return
+	}
+	var done []Position
+	for _, t := range b.Symmetry.Transforms() {
+		c := cell
+		c.Value = t.Char(c.Value)
+		for _, p := range t.Positions(Position{X: x, Y: y}) {
+			if slices.Contains(done, p) {
+				continue
+			}
+			done = append(done, p)
+			b.SetCell(p.X, p.Y, c, mask)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package adraw
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func bufferRows(b *Buffer) []string {
+	var rows []string
+	for y := range b.Data.Height {
+		var row []byte
+		for x := range b.Data.Width {
+			row = append(row, b.Data.MustGet(x, y).Value)
+		}
+		rows = append(rows, string(row))
+	}
+	return rows
+}
+
+func TestSymmetry(t *testing.T) {
+	dot := MakeGrid(1, 1, true)
+	tests := []struct {
+		name     string
+		sym      Symmetry
+		w, h     int
+		at       Position
+		char     byte
+		wantRows []string
+	}{
+		{
+			"horizontal between cells",
+			Symmetry{Mode: SymmetryHorizontal, CenterX: 2.5},
+			6, 1, Position{}, '/',
+			[]string{"/    \\"},
+		},
+		{
+			"on the axis",
+			Symmetry{Mode: SymmetryHorizontal, CenterX: 2},
+			5, 1, Position{X: 2}, '(',
+			[]string{"  (  "},
+		},
+		{
+			"vertical",
+			Symmetry{Mode: SymmetryVertical, CenterY: 1},
+			2, 3, Position{}, '^',
+			[]string{"^ ", "  ", "v "},
+		},
+		{
+			"both",
+			Symmetry{Mode: SymmetryBoth, CenterX: 2, CenterY: 1},
+			5, 3, Position{}, '/',
+			[]string{"/   \\", "     ", "\\   /"},
+		},
+		{
+			// Cells are twice as tall as they are wide, so quarter turns stretch them
+			"radial",
+			Symmetry{Mode: SymmetryRadial, CenterX: 4, CenterY: 2, Ways: 4},
+			9, 5, Position{X: 6, Y: 2}, '>',
+			[]string{"         ", "   ^^^   ", "  <   >  ", "   vvv   ", "         "},
+		},
+	}
+	for _, tt := range tests {
+		b := MakeBuffer(tt.w, tt.h)
+		b.Symmetry = tt.sym
+		b.PaintBrush(dot, tt.at, Cell{Value: tt.char}, 0)
+		for y, got := range bufferRows(b) {
+			if got != tt.wantRows[y] {
+				t.Errorf("%s: row %d is %q, want %q", tt.name, y, got, tt.wantRows[y])
+			}
+		}
+	}
+}
+
+func TestSymmetryFillRegion(t *testing.T) {
+	b := MakeBuffer(4, 1)
+	b.Symmetry = Symmetry{Mode: SymmetryHorizontal, CenterX: 1.5}
+	b.FillRegion(0, 0, 1, 1, Cell{Value: '#'}, 0)
+	if got := bufferRows(b)[0]; got != "#  #" {
+		t.Errorf("row is %q, want %q", got, "#  #")
+	}
+}
+
+// Number of groups of painted cells that touch each other, including diagonally.
+func paintedGroups(b *Buffer) int {
+	seen := MakeGrid(b.Data.Width, b.Data.Height, false)
+	var groups int
+	for y := range b.Data.Height {
+		for x := range b.Data.Width {
+			if seen.MustGet(x, y) || b.Data.MustGet(x, y).Value != '#' {
+				continue
+			}
+			groups++
+			stack := []Position{{X: x, Y: y}}
+			seen.Set(x, y, true)
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						q := Position{X: p.X + dx, Y: p.Y + dy}
+						if s, ok := seen.Get(q.X, q.Y); ok && !s && b.Data.MustGet(q.X, q.Y).Value == '#' {
+							seen.Set(q.X, q.Y, true)
+							stack = append(stack, q)
+						}
+					}
+				}
+			}
+		}
+	}
+	return groups
+}
+
+func TestRadialSymmetryHasNoHoles(t *testing.T) {
+	dot := MakeGrid(1, 1, true)
+	for _, ways := range []int{3, 4, 5, 6, 8} {
+		for _, line := range [][2]Position{
+			{{X: 38, Y: 15}, {X: 54, Y: 15}},
+			{{X: 30, Y: 19}, {X: 30, Y: 27}},
+			{{X: 36, Y: 18}, {X: 48, Y: 25}},
+		} {
+			b := MakeBuffer(61, 31)
+			b.Symmetry = Symmetry{Mode: SymmetryRadial, CenterX: 30, CenterY: 15, Ways: ways}
+			points := LinePositions(line[0].X, line[0].Y, line[1].X, line[1].Y)
+			b.BrushStrokes(dot, Cell{Value: '#'}, points, 0)
+			// Each copy is unbroken, and the copies stay apart
+			if got := paintedGroups(b); got != ways {
+				t.Errorf("%d ways, line %v: painted %d separate groups, want %d", ways, line, got, ways)
+			}
+		}
+	}
+}
+
+func TestSymmetryStamp(t *testing.T) {
+	b := MakeBuffer(6, 1)
+	b.Symmetry = Symmetry{Mode: SymmetryHorizontal, CenterX: 2.5}
+	stamp := MakeGrid(2, 1, Cell{Value: '('})
+	stamp.Set(1, 0, Cell{Value: '/'})
+	b.Stamp(stamp, Position{}, 0, false)
+	if got := bufferRows(b)[0]; got != "(/  \\)" {
+		t.Errorf("row is %q, want %q", got, "(/  \\)")
+	}
+}
+
+// Operations that already cover the whole selection are not repeated by the symmetry.
+func TestSymmetryAreaFills(t *testing.T) {
+	b := MakeBuffer(4, 1)
+	b.Symmetry = Symmetry{Mode: SymmetryHorizontal, CenterX: 1.5}
+	mask := MakeGrid(2, 1, true)
+	b.SetSelection(mask, Position{})
+	b.FillSelection(Cell{Value: '/'}, 0)
+	b.Deselect()
+	if got := bufferRows(b)[0]; got != "//  " {
+		t.Errorf("filled selection is %q, want %q", got, "//  ")
+	}
+
+	b = MakeBuffer(4, 1)
+	b.Symmetry = Symmetry{Mode: SymmetryHorizontal, CenterX: 1.5}
+	b.TileFill(MakeGrid(1, 1, Cell{Value: '/'}), Position{}, 0, false)
+	b.Gradient(Position{}, Position{X: 3}, nil, []tcell.Color{tcell.ColorRed}, false, 0)
+	if got := bufferRows(b)[0]; got != "////" {
+		t.Errorf("tiled and graded row is %q, want %q", got, "////")
+	}
+}
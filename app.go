@@ -1,11 +1,12 @@
 package main
 
 import (
-	"errors"
 	"log"
+	"math/rand/v2"
 	"os"
 	"time"
 
+	action "github.com/Fekinox/ascii-draw/internal"
 	"github.com/gdamore/tcell/v2"
 )
 
@@ -20,52 +21,136 @@ type App struct {
 
 	WillQuit bool
 
+	// If set, the screen is cleared before the next frame. Otherwise, widgets are expected
+	// to only repaint what changed.
+	needsClear bool
+
+	Config *Config
+	// Settings from startup, including the configuration above as it was initially.
+	UserData *UserData
+
+	// If set, nothing is written to disk: saving, exporting and storing settings succeed
+	// without touching any files. Used when replaying recordings.
+	DryRun bool
+	// If set, every event is written to a recording.
+	recorder *EventRecorder
+
+	editor *Editor
+
+	Screen        tcell.Screen
 	LogFileHandle *os.File
 	Logger        *log.Logger
 }
 
-func NewApp() *App {
+// Settings the editor starts with. Interactive sessions read them from the configuration
+// directory, while replays take them from the recording.
+type UserData struct {
+	Config *Config
+	Keymap map[KeyEvent]action.Action
+	Macros map[rune]Macro
+	// Clipboard registers, only read from disk if the configuration asks to persist them.
+	Registers *ClipboardRegisters
+	// Seed of the random choices made by tools, so that replays make the same ones.
+	Seed uint64
+
+	// Errors from reading the files, reported once the editor is up.
+	ConfigErr    error
+	KeymapErr    error
+	MacrosErr    error
+	RegistersErr error
+}
+
+// Default settings, as if the configuration directory were empty.
+func DefaultUserData() *UserData {
+	return &UserData{
+		Config:    DefaultConfig(),
+		Keymap:    defaultKeymap(),
+		Macros:    map[rune]Macro{},
+		Registers: NewClipboardRegisters(),
+	}
+}
+
+// Reads the configuration, keymap and macros from the configuration directory. Files that
+// fail to load are replaced by the defaults.
+func LoadUserData() *UserData {
+	d := DefaultUserData()
+	d.Seed = rand.Uint64()
+	d.Config, d.ConfigErr = LoadConfigFile()
+	d.KeymapErr = LoadKeymapFile(d.Keymap)
+	d.Macros, d.MacrosErr = LoadMacroFile()
+	if d.Config.PersistRegisters {
+		d.Registers, d.RegistersErr = LoadRegistersFile()
+	}
+	return d
+}
+
+// Starts the interactive editor on the terminal. If recordPath is not empty, the session is
+// recorded to that file.
+func NewApp(recordPath string) *App {
 	s, err := tcell.NewScreen()
 	if err != nil {
 		log.Fatalf("%+v", err)
 	}
-	Screen = s
-	if err := Screen.Init(); err != nil {
+	if err := s.Init(); err != nil {
 		log.Fatalf("%+v", err)
 	}
 
-	Screen.SetStyle(defStyle)
-	Screen.EnableMouse()
-	Screen.EnablePaste()
-	Screen.Clear()
-
-	if !Screen.HasMouse() {
-		log.Fatalf("%+v", errors.New("no mouse"))
-	}
+	s.SetStyle(defStyle)
+	s.EnableMouse()
+	s.EnablePaste()
+	s.Clear()
 
-	app := &App{
-		DefaultStyle: tcell.StyleDefault.Background(tcell.ColorReset).
-			Foreground(tcell.ColorReset),
-	}
+	data := LoadUserData()
 
 	// Initialize logger
-	app.LogFileHandle, err = os.Create("logfile")
+	logFile, err := os.Create(data.Config.LogFile)
 	if err != nil {
+		s.Fini()
 		log.Fatalf("%+v", err)
 	}
 
-	app.Logger = log.New(app.LogFileHandle, "", log.Flags())
+	var recorder *EventRecorder
+	if recordPath != "" {
+		width, height := s.Size()
+		recorder, err = NewEventRecorder(recordPath, NewRecordingHeader(width, height, data))
+		if err != nil {
+			s.Fini()
+			log.Fatalf("%+v", err)
+		}
+	}
+
+	app := NewAppWithScreen(s, log.New(logFile, "", log.Flags()), data)
+	app.LogFileHandle = logFile
+	app.recorder = recorder
+	return app
+}
+
+// Creates an app drawing to an already initialized screen.
+func NewAppWithScreen(s tcell.Screen, logger *log.Logger, data *UserData) *App {
+	app := &App{
+		Screen: s,
+		DefaultStyle: tcell.StyleDefault.Background(tcell.ColorReset).
+			Foreground(tcell.ColorReset),
+		needsClear: true,
+		Config:     data.Config,
+		UserData:   data,
+		Logger:     logger,
+	}
 
-	app.widget = NewMultiWidget(
-		Init(app, Screen),
-	)
+	app.editor = Init(app, s)
+	app.widget = NewMultiWidget(app.editor)
 
 	return app
 }
 
 func (a *App) Quit() {
 	maybePanic := recover()
-	Screen.Fini()
+	a.Screen.Fini()
+	if a.recorder != nil {
+		if err := a.recorder.Close(); err != nil {
+			a.Logger.Printf("Error closing recording: %v", err)
+		}
+	}
 	if maybePanic != nil {
 		a.Logger.Printf("Panic: %v\n", maybePanic)
 		log.Fatalf("Panic: %v\n", maybePanic)
@@ -75,64 +160,144 @@ func (a *App) Quit() {
 }
 
 func (a *App) Loop() {
+	events := make(chan tcell.Event, 64)
+	// Closed when the loop returns, so that the poller does not block on a full channel
+	// that nobody reads anymore.
+	done := make(chan struct{})
+	defer close(done)
+	go a.pollEvents(events, done)
+
 	lag := 0.0
 	prevTime := time.Now()
+	dirty := true
 
-	for {
-		currTime := time.Now()
-		elapsed := float64(currTime.Sub(prevTime).Nanoseconds()) / (1000 * 1000)
-		lag += elapsed * TIME_SCALE
-		prevTime = currTime
+	// The ticker only runs while some widget is animating; otherwise the loop sleeps until
+	// the next event arrives.
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
 
+	for {
 		if a.WillQuit {
 			return
 		}
 
-		// Event handling
-		for Screen.HasPendingEvent() {
-			ev := Screen.PollEvent()
-			switch ev := ev.(type) {
-			case *tcell.EventResize:
-				Screen.Sync()
-				a.widget.HandleEvent(ev)
-			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyCtrlL {
-					Screen.Sync()
-				} else {
-					a.widget.HandleEvent(ev)
+		if dirty {
+			a.Draw(lag)
+			dirty = false
+		}
+
+		if animating := a.widget.Animating(); animating && ticker == nil {
+			ticker = time.NewTicker(time.Second / time.Duration(FRAMES_PER_SECOND))
+			tick = ticker.C
+			lag = 0
+			prevTime = time.Now()
+		} else if !animating && ticker != nil {
+			ticker.Stop()
+			ticker, tick = nil, nil
+		}
+
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			a.HandleEvent(ev)
+			// Handle every event that is already queued before redrawing
+			for pending := true; pending; {
+				select {
+				case ev, ok := <-events:
+					if !ok {
+						return
+					}
+					a.HandleEvent(ev)
+				default:
+					pending = false
+				}
+			}
+			dirty = true
+		case currTime := <-tick:
+			elapsed := float64(currTime.Sub(prevTime).Nanoseconds()) / (1000 * 1000)
+			lag += elapsed * TIME_SCALE
+			prevTime = currTime
+
+			for lag >= UPDATE_TICK_RATE_MS {
+				dirty = true
+				a.widget.Update()
+				if a.editor.WantsTicks() {
+					a.HandleEvent(NewEventTick())
 				}
-			default:
-				a.widget.HandleEvent(ev)
+				lag -= UPDATE_TICK_RATE_MS
 			}
 		}
+	}
+}
 
-		dirty := false
-		for lag >= UPDATE_TICK_RATE_MS {
-			dirty = true
-			a.widget.Update()
-			lag -= UPDATE_TICK_RATE_MS
+// Feeds events from the screen into the given channel until the screen is finalized or done
+// is closed.
+func (a *App) pollEvents(events chan<- tcell.Event, done <-chan struct{}) {
+	for {
+		ev := a.Screen.PollEvent()
+		if ev == nil {
+			close(events)
+			return
+		}
+		select {
+		case events <- ev:
+		case <-done:
+			return
 		}
+	}
+}
 
-		if dirty {
-			a.Draw(lag)
+func (a *App) HandleEvent(ev tcell.Event) {
+	if a.recorder != nil {
+		if err := a.recorder.Record(ev); err != nil {
+			a.Logger.Printf("Error recording event, recording stopped: %v", err)
+			a.recorder.Close()
+			a.recorder = nil
+		}
+	}
+
+	switch ev := ev.(type) {
+	case *tcell.EventResize:
+		a.Screen.Sync()
+		a.needsClear = true
+		a.widget.HandleEvent(ev)
+	case *tcell.EventKey:
+		if ev.Key() == tcell.KeyCtrlL {
+			a.Screen.Sync()
+		} else {
+			a.widget.HandleEvent(ev)
 		}
+	default:
+		a.widget.HandleEvent(ev)
 	}
 }
 
 func (a *App) Draw(lag float64) {
-	Screen.Clear()
+	sw, sh := a.Screen.Size()
 
-	sw, sh := Screen.Size()
-
-	p := Paint
+	p := DefaultPainter{Screen: a.Screen}
 
 	if sw < MIN_WIDTH || sh < MIN_HEIGHT {
+		a.Screen.Clear()
+		a.needsClear = true
 		ShowResizeScreen(p, sw, sh, defStyle)
-		Screen.Show()
+		a.Screen.Show()
 		return
 	}
 
+	if a.needsClear {
+		a.Screen.Clear()
+		a.needsClear = false
+	}
+
 	a.widget.Draw(p, 0, 0, sw, sh, lag)
 
-	Screen.Show()
+	a.Screen.Show()
 }
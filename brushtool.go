@@ -1,92 +1,174 @@
 package main
 
 import (
-	"time"
+	"errors"
+	"slices"
+	"strings"
 
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
 	"github.com/gdamore/tcell/v2"
 )
 
+func init() {
+	RegisterTool(ToolInfo{
+		Name:        "brush",
+		Action:      action.Brush,
+		Description: "paint with the brush character and colors (default tool)",
+		Gestures: []string{
+			"click and drag: paint",
+			"right click and drag: erase",
+			"tab: toggle straight line mode",
+			"(line mode) click and drag: draw a line",
+		},
+	})
+}
+
 type BrushTool struct {
+	// Erase with the left button too, making this the eraser tool.
+	Eraser bool
+
 	isDragging bool
+	// Whether the current stroke erases.
+	erasing bool
 
 	lineMode bool
-	start    Position
+	start    adraw.Position
+
+	lastPaintPos adraw.Position
+
+	// Number of times the brush was put down in the current stroke, which picks the ramp
+	// character of the pattern brush.
+	step int
+}
+
+var (
+	_ Tool = &BrushTool{}
+)
+
+// Mask of the current brush, centered on the cell at (Width/2, Height/2).
+func (m *Editor) BrushMask() adraw.Grid[bool] {
+	if m.brushShape == adraw.BrushCustom {
+		return m.customBrush
+	}
+	return adraw.BrushMask(m.brushShape, m.brushRadius)
+}
+
+// Switches the brush shape. The custom shape is taken from the current selection, so it
+// fails if nothing is selected.
+func (m *Editor) SetBrushShape(shape adraw.BrushShape) error {
+	if shape == adraw.BrushCustom {
+		brush, ok := m.CurrentCanvas().SelectionBrush()
+		if !ok {
+			return errors.New("nothing selected")
+		}
+		m.customBrush = brush
+	}
+	m.brushShape = shape
+	return nil
+}
+
+// Cell painted by the brush the given number of steps into a stroke.
+func (m *Editor) brushCell(step int) adraw.Cell {
+	v := m.brushCharacter
+	if m.patternBrush && len(m.ramp) > 0 {
+		v = m.ramp[step%len(m.ramp)]
+	}
+	return adraw.Cell{
+		Value: v,
+		Style: tcell.StyleDefault.Foreground(m.fgColor).Background(m.bgColor),
+	}
+}
+
+// Look of the cells under the brush preview for a stroke that paints or erases.
+func (m *Editor) brushPreviewCell(erasing bool, step int) adraw.Cell {
+	if erasing {
+		return adraw.Cell{Value: ' ', Style: tcell.StyleDefault.Reverse(true)}
+	}
+	return m.brushCell(step)
+}
 
-	lastPaint    time.Time
-	lastPaintPos Position
+// Puts the brush down at each of the points, moving the pattern on by one step each time.
+func (b *BrushTool) paint(m *Editor, points []adraw.Position) {
+	brush := m.BrushMask()
+	for _, pt := range points {
+		if b.erasing {
+			m.stagingCanvas.PaintBrush(brush, pt, eraseCell, m.EraseLockMask())
+		} else {
+			m.stagingCanvas.PaintBrush(brush, pt, m.brushCell(b.step), m.lockMask)
+		}
+		b.step++
+	}
+}
+
+// Draws the cells of a brush mask centered on a screen position.
+func DrawBrush(p Painter, brush adraw.Grid[bool], x, y int, v byte, style tcell.Style) {
+	left, top := x-brush.Width/2, y-brush.Height/2
+	for dy := range brush.Height {
+		for dx := range brush.Width {
+			if brush.MustGet(dx, dy) {
+				p.SetByte(left+dx, top+dy, v, style)
+			}
+		}
+	}
 }
 
 func (b *BrushTool) HandleEvent(m *Editor, event tcell.Event) {
 	switch ev := event.(type) {
 	case *tcell.EventMouse:
-		cx, cy := m.cursorX-m.brushRadius/2-m.offsetX, m.cursorY-m.brushRadius/2-m.offsetY
-		p := Position{X: cx, Y: cy}
-		cell := Cell{
-			Value: m.brushCharacter,
-			Style: tcell.StyleDefault.Foreground(m.fgColor).Background(m.bgColor),
+		p := m.cursorCanvasPosition()
+		held := ev.Buttons()&(tcell.Button1|ERASE_BUTTONS) != 0
+		if held && !b.isDragging {
+			b.erasing = b.Eraser || ev.Buttons()&tcell.Button1 == 0
 		}
 
 		if !b.lineMode {
-			if ev.Buttons()&tcell.Button1 != 0 {
+			if held {
+				wasDragging := b.isDragging
 				if !b.isDragging {
 					b.isDragging = true
+					b.step = 0
 				}
 
 				m.Stage()
-				cell := Cell{
-					Value: m.brushCharacter,
-					Style: tcell.StyleDefault.Foreground(m.fgColor).Background(m.bgColor),
-				}
 
-				if ev.When().Sub(b.lastPaint).Seconds() < 0.1 {
-					dx, dy := cx-b.lastPaintPos.X, cy-b.lastPaintPos.Y
+				// Join up with the last position of the drag, so that fast strokes and
+				// keyboard cursor moves leave no gaps. This only depends on the order of
+				// events, so that replayed events paint the same strokes.
+				if wasDragging {
+					dx, dy := p.X-b.lastPaintPos.X, p.Y-b.lastPaintPos.Y
 					dist := max(max(dx, -dx), max(dy, -dy))
 					if dist > 1 {
-						posns := LinePositions(b.lastPaintPos.X, b.lastPaintPos.Y, cx, cy)
-						for _, pt := range posns[1 : len(posns)-1] {
-							m.stagingCanvas.FillRegion(
-								pt.X, pt.Y, m.brushRadius, m.brushRadius, cell, m.lockMask,
-							)
-						}
+						posns := adraw.LinePositions(b.lastPaintPos.X, b.lastPaintPos.Y, p.X, p.Y)
+						b.paint(m, posns[1:len(posns)-1])
 					}
-					m.stagingCanvas.FillRegion(
-						p.X, p.Y, m.brushRadius, m.brushRadius, cell, m.lockMask,
-					)
-				} else {
-					m.stagingCanvas.FillRegion(
-						p.X, p.Y, m.brushRadius, m.brushRadius, cell, m.lockMask,
-					)
 				}
+				b.paint(m, []adraw.Position{p})
 			} else if b.isDragging {
 				b.isDragging = false
 				m.Commit()
 			}
 		} else {
-			if ev.Buttons()&tcell.Button1 != 0 {
+			if held {
 				if !b.isDragging {
 					b.isDragging = true
-					b.start = Position{X: cx, Y: cy}
+					b.start = p
 				}
 			} else if b.isDragging {
 				b.isDragging = false
 				m.Stage()
-				linePositions := LinePositions(b.start.X, b.start.Y, cx, cy)
-				m.stagingCanvas.BrushStrokes(m.brushRadius, cell, linePositions, m.lockMask)
+				b.step = 0
+				b.paint(m, adraw.LinePositions(b.start.X, b.start.Y, p.X, p.Y))
 				m.Commit()
 			}
 		}
 		b.lastPaintPos = p
-		b.lastPaint = ev.When()
 	case *tcell.EventKey:
 		if ev.Key() == tcell.KeyTab {
 			if b.lineMode {
 				m.Stage()
-				cell := Cell{
-					Value: m.brushCharacter,
-					Style: tcell.StyleDefault.Foreground(m.fgColor).Background(m.bgColor),
-				}
-				linePositions := LinePositions(b.start.X, b.start.Y, b.lastPaintPos.X, b.lastPaintPos.Y)
-				m.stagingCanvas.BrushStrokes(m.brushRadius, cell, linePositions, m.lockMask)
+				b.step = 0
+				b.paint(m, adraw.LinePositions(b.start.X, b.start.Y, b.lastPaintPos.X, b.lastPaintPos.Y))
 				m.Commit()
 			} else {
 				m.Commit()
@@ -98,32 +180,70 @@ func (b *BrushTool) HandleEvent(m *Editor, event tcell.Event) {
 }
 
 func (b *BrushTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
+	title := "Brush Tool"
+	var modes []string
+	if b.Eraser {
+		title = "Eraser Tool"
+		modes = append(modes, m.eraseMode.String())
+	}
 	if b.lineMode {
-		SetString(p, x+m.sx, y+m.sy-1, "Brush Tool (straight lines)", tcell.StyleDefault)
-	} else {
-		SetString(p, x+m.sx, y+m.sy-1, "Brush Tool", tcell.StyleDefault)
+		modes = append(modes, "straight lines")
+	}
+	if m.patternBrush && !b.Eraser {
+		modes = append(modes, "pattern")
+	}
+	if len(modes) > 0 {
+		title += " (" + strings.Join(modes, ", ") + ")"
 	}
+	SetString(p, x+m.sx, y+m.sy-1, title, tcell.StyleDefault)
 	if b.isDragging && b.lineMode {
+		canvas := m.CurrentCanvas()
 		crop := &CropPainter{
 			p: p,
-			area: Area{
+			area: adraw.Area{
 				X:      m.offsetX + m.sx,
 				Y:      m.offsetY + m.sy,
-				Width:  m.canvas.Data.Width,
-				Height: m.canvas.Data.Height,
+				Width:  canvas.Data.Width,
+				Height: canvas.Data.Height,
 			},
 		}
-		for _, pt := range LinePositions(
-			b.start.X, b.start.Y,
-			m.cursorX-m.offsetX, m.cursorY-m.offsetY) {
-			if m.canvas.Data.InBounds(pt.X, pt.Y) {
-				FillRegion(
-					crop,
-					pt.X-m.brushRadius/2+m.offsetX+m.sx, pt.Y-m.brushRadius/2+m.offsetY+m.sy,
-					m.brushRadius, m.brushRadius,
-					rune(m.brushCharacter),
-					tcell.StyleDefault.Foreground(m.fgColor).Background(m.bgColor),
-				)
+		brush := m.BrushMask()
+		end := m.cursorCanvasPosition()
+		for i, pt := range adraw.LinePositions(b.start.X, b.start.Y, end.X, end.Y) {
+			cell := m.brushPreviewCell(b.erasing, i)
+			cell.Style = m.palette.Style(cell.Style)
+			DrawMirroredBrush(crop, m.symmetry, brush, pt, m.offsetX+m.sx, m.offsetY+m.sy, cell)
+		}
+	}
+}
+
+// Draws the cells of a brush mask centered on a canvas position, along with their copies
+// under the symmetry, like painting the brush there would. The canvas origin is at the
+// screen position (offX, offY).
+func DrawMirroredBrush(
+	p Painter,
+	sym adraw.Symmetry,
+	brush adraw.Grid[bool],
+	center adraw.Position,
+	offX, offY int,
+	cell adraw.Cell,
+) {
+	transforms := sym.Transforms()
+	left, top := center.X-brush.Width/2, center.Y-brush.Height/2
+	for dy := range brush.Height {
+		for dx := range brush.Width {
+			if !brush.MustGet(dx, dy) {
+				continue
+			}
+			var done []adraw.Position
+			for _, t := range transforms {
+				for _, q := range t.Positions(adraw.Position{X: left + dx, Y: top + dy}) {
+					if slices.Contains(done, q) {
+						continue
+					}
+					done = append(done, q)
+					p.SetByte(q.X+offX, q.Y+offY, t.Char(cell.Value), cell.Style)
+				}
 			}
 		}
 	}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+)
+
+// A headless subcommand, run instead of the interactive editor when named on the command
+// line.
+type Subcommand struct {
+	Name        string
+	Usage       string
+	Description string
+	Run         func(c *Subcommand, args []string) error
+}
+
+var subcommands []*Subcommand
+
+func init() {
+	subcommands = []*Subcommand{
+		{
+			Name:        "convert",
+			Usage:       "convert [-to format] [-palette file] <input> <output>",
+			Description: "Convert a drawing between the binary, text, ANSI, HTML and PNG formats",
+			Run:         runConvert,
+		},
+		{
+			Name:        "info",
+			Usage:       "info <file>",
+			Description: "Print the dimensions, colors and format of a drawing",
+			Run:         runInfo,
+		},
+		{
+			Name:        "cat",
+			Usage:       "cat [-x n] [-y n] [-w n] [-h n] [-fit] [-color mode] [-pager] <file>",
+			Description: "Print a drawing to the terminal with its colors",
+			Run:         runCat,
+		},
+		{
+			Name:        "new",
+			Usage:       "new [-w width] [-h height] <output>",
+			Description: "Create a blank drawing",
+			Run:         runNew,
+		},
+		{
+			Name:        "replay",
+			Usage:       "replay [-o output] [-screen] <recording>",
+			Description: "Replay a session recorded with -record and print a checksum of the final canvas",
+			Run:         runReplay,
+		},
+		{
+			Name:        "help",
+			Usage:       "help",
+			Description: "Show this message",
+			Run:         runHelp,
+		},
+	}
+}
+
+var ErrUnknownSubcommand = errors.New("unknown subcommand")
+
+// Runs the subcommand named by the first argument.
+func RunSubcommand(args []string) error {
+	idx := slices.IndexFunc(subcommands, func(c *Subcommand) bool {
+		return c.Name == args[0]
+	})
+	if idx == -1 {
+		printUsage(os.Stderr)
+		return fmt.Errorf("%w %q", ErrUnknownSubcommand, args[0])
+	}
+	c := subcommands[idx]
+	if err := c.Run(c, args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "usage: ascii-draw [-record file] [subcommand]\n\n")
+	fmt.Fprintf(w, "Without a subcommand, starts the interactive editor. With -record, every\n")
+	fmt.Fprintf(w, "event of the session is written to the given file for replaying.\n\n")
+	fmt.Fprintf(w, "subcommands:\n")
+	for _, c := range subcommands {
+		fmt.Fprintf(w, "  %s\n      %s\n", c.Usage, c.Description)
+	}
+}
+
+func (c *Subcommand) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.Name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: ascii-draw %s\n", c.Usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func runConvert(c *Subcommand, args []string) error {
+	fs := c.flagSet()
+	to := fs.String("to", "", "output format; inferred from the output extension if empty")
+	palette := fs.String("palette", "", "palette file (.gpl or JSON) to export the colors with")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("convert takes an input and an output file")
+	}
+
+	b, _, err := adraw.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *palette != "" {
+		if b.Palette, err = adraw.ReadPaletteFile(*palette); err != nil {
+			return err
+		}
+	}
+
+	format := adraw.Format(*to)
+	if format == "" {
+		format = adraw.FormatFromPath(fs.Arg(1))
+	}
+	return b.WriteFile(fs.Arg(1), format)
+}
+
+func runInfo(c *Subcommand, args []string) error {
+	fs := c.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("info takes a single file")
+	}
+
+	b, format, err := adraw.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case adraw.FormatBinary:
+		fmt.Printf("format:     ascii-draw binary, version %d\n", adraw.FormatVersion)
+	default:
+		fmt.Printf("format:     plain text\n")
+	}
+	fmt.Printf("dimensions: %d x %d\n", b.Data.Width, b.Data.Height)
+
+	fgCounts, bgCounts := map[tcell.Color]int{}, map[tcell.Color]int{}
+	var blank int
+	for y := range b.Data.Height {
+		for x := range b.Data.Width {
+			cell := b.Data.MustGet(x, y)
+			fg, bg, _ := cell.Style.Decompose()
+			if cell.IsZero() {
+				blank++
+			}
+			if cell.Value != ' ' {
+				fgCounts[fg]++
+			}
+			bgCounts[bg]++
+		}
+	}
+	fmt.Printf("blank cells: %d of %d\n", blank, b.Data.Width*b.Data.Height)
+	fmt.Printf("foreground colors:\n")
+	printColorCounts(fgCounts)
+	fmt.Printf("background colors:\n")
+	printColorCounts(bgCounts)
+
+	return nil
+}
+
+func printColorCounts(counts map[tcell.Color]int) {
+	colors := make([]tcell.Color, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+	slices.Sort(colors)
+
+	for _, c := range colors {
+		name := "default"
+		if c != tcell.ColorDefault {
+			name = c.Name()
+		}
+		fmt.Printf("  %-10s %d\n", name, counts[c])
+	}
+}
+
+func runNew(c *Subcommand, args []string) error {
+	// Like the editor, fall back to the defaults if the configuration cannot be used
+	config, err := LoadConfigFile()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ascii-draw: using the default settings: %v\n", err)
+		config = DefaultConfig()
+	}
+
+	fs := c.flagSet()
+	width := fs.Int("w", config.Width, "canvas width")
+	height := fs.Int("h", config.Height, "canvas height")
+	to := fs.String("to", "", "output format; inferred from the output extension if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("new takes a single output file")
+	}
+	if *width <= 0 || *height <= 0 {
+		return errors.New("width and height must be positive")
+	}
+
+	format := adraw.Format(*to)
+	if format == "" {
+		format = adraw.FormatFromPath(fs.Arg(0))
+	}
+	return adraw.MakeBuffer(*width, *height).WriteFile(fs.Arg(0), format)
+}
+
+func runReplay(c *Subcommand, args []string) error {
+	fs := c.flagSet()
+	output := fs.String("o", "", "write the final canvas to this file, in the format given by its extension")
+	screen := fs.Bool("screen", false, "print the final contents of the screen")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("replay takes a single recording")
+	}
+
+	header, events, err := ReadRecordingFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+	res, err := Replay(header, events, log.New(io.Discard, "", 0))
+	if err != nil {
+		return err
+	}
+
+	if *screen {
+		fmt.Print(ScreenText(res.Screen))
+	}
+	fmt.Printf("events: %d of %d\n", res.Events, len(events))
+	fmt.Printf("canvas: %d x %d\n", res.Canvas.Data.Width, res.Canvas.Data.Height)
+	fmt.Printf("sha256: %x\n", res.Checksum)
+
+	if *output != "" {
+		return res.Canvas.WriteFile(*output, adraw.FormatFromPath(*output))
+	}
+	return nil
+}
+
+func runHelp(c *Subcommand, args []string) error {
+	printUsage(os.Stdout)
+	return nil
+}
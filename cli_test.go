@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Runs a subcommand and returns what it printed to standard output.
+func runSubcommandOutput(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	runErr := RunSubcommand(args)
+	w.Close()
+	return <-done, runErr
+}
+
+func TestNewConvertInfo(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	drawing := filepath.Join(dir, "blank.adraw")
+
+	if _, err := runSubcommandOutput(t, "new", "-w", "6", "-h", "3", drawing); err != nil {
+		t.Fatal(err)
+	}
+	b, format, err := adraw.ReadFile(drawing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != adraw.FormatBinary || b.Data.Width != 6 || b.Data.Height != 3 {
+		t.Fatalf("new wrote a %d x %d %s drawing, want a 6 x 3 binary one", b.Data.Width, b.Data.Height, format)
+	}
+
+	// Color a cell so that info and the ANSI export have something to show
+	b.SetCell(1, 0, adraw.Cell{Value: 'x', Style: tcell.StyleDefault.Foreground(tcell.ColorRed)}, 0)
+	if err := b.WriteFile(drawing, adraw.FormatBinary); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runSubcommandOutput(t, "info", drawing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"ascii-draw binary", "dimensions: 6 x 3", "red        1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("info output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	text := filepath.Join(dir, "out.txt")
+	if _, err := runSubcommandOutput(t, "convert", drawing, text); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(text); err != nil || !strings.HasPrefix(string(data), " x    \n") {
+		t.Errorf("text export is %q (%v)", data, err)
+	}
+
+	ansi := filepath.Join(dir, "out.data")
+	if _, err := runSubcommandOutput(t, "convert", "-to", "ans", drawing, ansi); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(ansi); err != nil || !strings.Contains(string(data), "\x1b[91;49mx") {
+		t.Errorf("ANSI export is %q (%v)", data, err)
+	}
+}
+
+func TestSubcommandErrors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"frobnicate"}, "unknown subcommand"},
+		{[]string{"new"}, "new takes a single output file"},
+		{[]string{"new", "-w", "0", filepath.Join(dir, "x.adraw")}, "width and height must be positive"},
+		{[]string{"new", "-to", "bmp", filepath.Join(dir, "x.bmp")}, "bmp"},
+		{[]string{"convert", "in.adraw"}, "convert takes an input and an output file"},
+		{[]string{"convert", filepath.Join(dir, "missing.adraw"), "out.txt"}, "no such file"},
+		{[]string{"info"}, "info takes a single file"},
+	}
+	// Usage messages are printed to standard error
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = devNull
+	defer func() {
+		os.Stderr = stderr
+		devNull.Close()
+	}()
+	for _, tt := range tests {
+		_, err := runSubcommandOutput(t, tt.args...)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: got error %v, want %q", tt.args, err, tt.wantErr)
+		}
+	}
+
+	if _, err := runSubcommandOutput(t, "frobnicate"); !errors.Is(err, ErrUnknownSubcommand) {
+		t.Errorf("unknown subcommand gives %v", err)
+	}
+}
+
+func TestNewWithBadConfig(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	if err := os.MkdirAll(filepath.Join(config, "ascii-draw"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(config, "ascii-draw", "config.json"), []byte(`{"width": `), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() {
+		os.Stderr = stderr
+	}()
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+
+	drawing := filepath.Join(t.TempDir(), "blank.adraw")
+	_, runErr := runSubcommandOutput(t, "new", drawing)
+	w.Close()
+	warning := <-done
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+	if !strings.Contains(warning, "using the default settings") {
+		t.Errorf("new printed %q, want a warning about the configuration", warning)
+	}
+
+	// The drawing gets the default size
+	b, _, err := adraw.ReadFile(drawing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def := DefaultConfig(); b.Data.Width != def.Width || b.Data.Height != def.Height {
+		t.Errorf("new wrote a %d x %d drawing, want %d x %d", b.Data.Width, b.Data.Height, def.Width, def.Height)
+	}
+}
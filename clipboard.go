@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+)
+
+// Ways of reaching the system clipboard, see Config.Clipboard.
+const (
+	// OSC 52 on terminals that are not known to ignore it, a helper program otherwise.
+	CLIPBOARD_AUTO   = "auto"
+	CLIPBOARD_OSC52  = "osc52"
+	CLIPBOARD_HELPER = "helper"
+)
+
+// A program that copies its standard input to the system clipboard.
+type clipboardHelper struct {
+	name string
+	args []string
+	// Environment variable that must be set for the helper to work, if any.
+	env string
+}
+
+// Helpers in order of preference.
+var clipboardHelpers = []clipboardHelper{
+	{name: "wl-copy", env: "WAYLAND_DISPLAY"},
+	{name: "xclip", args: []string{"-selection", "clipboard"}, env: "DISPLAY"},
+	{name: "xsel", args: []string{"--clipboard", "--input"}, env: "DISPLAY"},
+	{name: "pbcopy"},
+	{name: "clip.exe"},
+}
+
+var errNoClipboardHelper = errors.New("no clipboard helper found, install wl-copy, xclip, xsel or pbcopy")
+
+// Text of a grid for the clipboard. Plain text lines have their trailing blanks removed;
+// with colors, every cell is kept and colored with ANSI SGR codes, in the colors the palette
+// shows it in.
+func ClipboardText(clip adraw.Grid[adraw.Cell], palette *adraw.Palette, colors bool) (string, error) {
+	var sb strings.Builder
+	if colors {
+		b := &adraw.Buffer{Data: clip, Palette: palette}
+		if err := b.ExportANSI(&sb); err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	}
+
+	line := make([]byte, clip.Width)
+	for y := range clip.Height {
+		for x := range clip.Width {
+			line[x] = clip.MustGet(x, y).Value
+			if line[x] == 0 {
+				line[x] = ' '
+			}
+		}
+		sb.WriteString(strings.TrimRight(string(line), " "))
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+// Whether the terminal is likely to act on OSC 52. Terminals that do not support the
+// sequence silently drop it, so there is no way to ask; instead, terminals known to drop it
+// are ruled out.
+func osc52Supported() bool {
+	switch os.Getenv("TERM") {
+	case "", "dumb", "linux":
+		return false
+	}
+	if os.Getenv("TERM_PROGRAM") == "Apple_Terminal" {
+		return false
+	}
+	// GNOME Terminal and the other terminals built on VTE
+	if os.Getenv("VTE_VERSION") != "" {
+		return false
+	}
+	return true
+}
+
+// OSC 52 sequence that sets the clipboard to s. Inside tmux, the sequence is wrapped so that
+// tmux passes it on to the outer terminal.
+func osc52Sequence(s string, tmux bool) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(s)) + "\x07"
+	if tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+func (m *Editor) writeOSC52(s string) error {
+	tty, ok := m.app.Screen.Tty()
+	if !ok {
+		return errors.New("not running in a terminal")
+	}
+	_, err := io.WriteString(tty, osc52Sequence(s, os.Getenv("TMUX") != ""))
+	return err
+}
+
+// Runs the first available helper program, returning its name.
+func runClipboardHelper(s string) (string, error) {
+	for _, h := range clipboardHelpers {
+		if h.env != "" && os.Getenv(h.env) == "" {
+			continue
+		}
+		path, err := exec.LookPath(h.name)
+		if err != nil {
+			continue
+		}
+		// Output is discarded rather than captured: xclip and wl-copy leave a process
+		// running to serve the clipboard, which would hold a captured pipe open
+		cmd := exec.Command(path, h.args...)
+		cmd.Stdin = strings.NewReader(s)
+		if err := cmd.Run(); err != nil {
+			return h.name, fmt.Errorf("%s: %w", h.name, err)
+		}
+		return h.name, nil
+	}
+	return "", errNoClipboardHelper
+}
+
+// Puts text on the system clipboard with the configured method, returning the name of the
+// method used.
+func (m *Editor) WriteSystemClipboard(s string) (string, error) {
+	if m.app.DryRun {
+		return "dry run", nil
+	}
+
+	method := m.config.Clipboard
+	if method == CLIPBOARD_AUTO {
+		method = CLIPBOARD_HELPER
+		if _, ok := m.app.Screen.Tty(); ok && osc52Supported() {
+			method = CLIPBOARD_OSC52
+		}
+	}
+
+	if method == CLIPBOARD_OSC52 {
+		return "OSC 52", m.writeOSC52(s)
+	}
+	return runClipboardHelper(s)
+}
+
+// Copies the selection, or the whole canvas if nothing is selected, to the system clipboard.
+func (m *Editor) SystemCopy(colors bool) {
+	canvas := m.CurrentCanvas()
+	clip := canvas.Data
+	if canvas.HasSelection() {
+		clip = canvas.CopySelection()
+	}
+
+	text, err := ClipboardText(clip, m.palette, colors)
+	var method string
+	if err == nil {
+		method, err = m.WriteSystemClipboard(text)
+	}
+	if err != nil {
+		m.app.Logger.Printf("Error copying to system clipboard: %v", err)
+		m.notification.PushNotification("Error copying to clipboard", err.Error(), NotificationCritical)
+		return
+	}
+
+	m.notification.PushNotification(
+		"",
+		fmt.Sprintf("Copied %d x %d to the system clipboard (%s)", clip.Width, clip.Height, method),
+		NotificationNormal,
+	)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestClipboardText(t *testing.T) {
+	clip := adraw.MakeGrid(4, 2, adraw.Cell{Value: ' '})
+	clip.Set(0, 0, adraw.Cell{Value: 'a'})
+	clip.Set(2, 0, adraw.Cell{Value: 'b', Style: tcell.StyleDefault.Foreground(tcell.ColorMaroon)})
+	clip.Set(1, 1, adraw.Cell{})
+
+	text, err := ClipboardText(clip, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a b\n\n"; text != want {
+		t.Errorf("plain text is %q, want %q", text, want)
+	}
+
+	colored, err := ClipboardText(clip, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(colored, "\x1b[31;49mb") || strings.Count(colored, "\n") != 2 {
+		t.Errorf("colored text %q does not color the cells", colored)
+	}
+
+	// Colors are copied as the palette shows them
+	palette := adraw.DefaultPalette()
+	palette.Entries[1].Color = tcell.NewRGBColor(0x12, 0x34, 0x56)
+	colored, err = ClipboardText(clip, palette, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(colored, "\x1b[38;2;18;52;86;49mb") {
+		t.Errorf("colored text %q does not use the colors of the palette", colored)
+	}
+}
+
+func TestOSC52Sequence(t *testing.T) {
+	if got, want := osc52Sequence("hi", false), "\x1b]52;c;aGk=\x07"; got != want {
+		t.Errorf("sequence is %q, want %q", got, want)
+	}
+	if got, want := osc52Sequence("hi", true), "\x1bPtmux;\x1b\x1b]52;c;aGk=\x07\x1b\\"; got != want {
+		t.Errorf("tmux sequence is %q, want %q", got, want)
+	}
+}
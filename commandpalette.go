@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Maximum number of matches listed in the command palette.
+const PALETTE_MAX_RESULTS = 12
+
+// An entry of the command palette: an action, a tool or a command with arguments.
+type paletteItem struct {
+	name        string
+	description string
+	keys        string
+
+	act     action.Action
+	command *Command
+}
+
+type paletteMatch struct {
+	item  *paletteItem
+	score int
+}
+
+// Modal tool that fuzzy-finds actions, tools and commands by name and runs them.
+type CommandPaletteTool struct {
+	items    []*paletteItem
+	input    TextWidget
+	matches  []paletteMatch
+	selected int
+	err      string
+}
+
+var (
+	_ Tool = &CommandPaletteTool{}
+)
+
+func NewCommandPaletteTool(keymap map[KeyEvent]action.Action) *CommandPaletteTool {
+	t := &CommandPaletteTool{
+		input: TextWidget{
+			Active: true,
+			Hint:   "type a command...",
+		},
+	}
+	for _, act := range action.All() {
+		t.items = append(t.items, &paletteItem{
+			name:        act.String(),
+			description: act.Description(),
+			keys:        keyList(keymap, act),
+			act:         act,
+		})
+	}
+	for _, info := range Tools() {
+		t.items = append(t.items, &paletteItem{
+			name:        info.Name + " tool",
+			description: info.Description,
+			keys:        keyList(keymap, info.Action),
+			act:         info.Action,
+		})
+	}
+	for _, c := range commands {
+		t.items = append(t.items, &paletteItem{
+			name:        c.Usage(),
+			description: c.Description,
+			command:     c,
+		})
+	}
+	t.updateMatches()
+	return t
+}
+
+// Scores how well the query matches the target as a subsequence, ignoring case. Consecutive
+// characters and characters at the start of words score higher.
+func fuzzyScore(query, target string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	t := []rune(strings.ToLower(target))
+	if len(q) == 0 {
+		return 0, true
+	}
+
+	var score, qi int
+	prevMatch := -2
+	for ti, r := range t {
+		if qi == len(q) {
+			break
+		}
+		if r != q[qi] {
+			continue
+		}
+		score++
+		if ti == prevMatch+1 {
+			score += 3
+		}
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) {
+			score += 2
+		}
+		prevMatch = ti
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	// Prefer shorter targets among equal matches
+	return score*100 - len(t), true
+}
+
+func (e *CommandPaletteTool) updateMatches() {
+	query := strings.TrimSpace(e.input.Contents)
+	cmd, args, hasCmd := FindCommand(query)
+	typingArgs := hasCmd && (len(args) > 0 || strings.HasSuffix(e.input.Contents, " "))
+
+	e.matches = e.matches[:0]
+	for _, item := range e.items {
+		if typingArgs && item.command == cmd {
+			// Arguments are being typed, so the command always comes first
+			e.matches = append(e.matches, paletteMatch{item: item, score: 1 << 30})
+			continue
+		}
+		if score, ok := fuzzyScore(strings.ReplaceAll(query, " ", ""), item.name); ok {
+			e.matches = append(e.matches, paletteMatch{item: item, score: score})
+		}
+	}
+	slices.SortStableFunc(e.matches, func(a, b paletteMatch) int {
+		return b.score - a.score
+	})
+	e.selected = 0
+}
+
+func (e *CommandPaletteTool) HandleEvent(m *Editor, event tcell.Event) {
+	ev, ok := event.(*tcell.EventKey)
+	if !ok {
+		return
+	}
+
+	switch ev.Key() {
+	case tcell.KeyUp:
+		e.selected = max(0, e.selected-1)
+	case tcell.KeyDown:
+		e.selected = max(0, min(len(e.matches)-1, e.selected+1))
+	case tcell.KeyTab:
+		if e.selected < len(e.matches) {
+			e.complete(e.matches[e.selected].item)
+		}
+	case tcell.KeyEnter:
+		e.submit(m)
+	default:
+		old := e.input.Contents
+		e.input.HandleEvent(ev)
+		if e.input.Contents != old {
+			e.err = ""
+			e.updateMatches()
+		}
+	}
+}
+
+// Replaces the input with the name of the item, ready for arguments to be typed.
+func (e *CommandPaletteTool) complete(item *paletteItem) {
+	if item.command != nil {
+		e.input.SetContents(item.command.Name + " ")
+	} else {
+		e.input.SetContents(item.name)
+	}
+	e.updateMatches()
+}
+
+// Runs the selected entry. Input with arguments runs as a command instead, since only
+// commands take arguments.
+func (e *CommandPaletteTool) submit(m *Editor) {
+	if cmd, args, ok := FindCommand(e.input.Contents); ok && len(args) > 0 {
+		e.runCommand(m, cmd, args)
+		return
+	}
+	if e.selected >= len(e.matches) {
+		return
+	}
+
+	item := e.matches[e.selected].item
+	if item.command != nil {
+		if item.command.NeedsArgs() {
+			// Prompt for the arguments
+			e.complete(item)
+		} else {
+			e.runCommand(m, item.command, nil)
+		}
+		return
+	}
+	m.ClearModalTool()
+	m.RunAction(item.act)
+}
+
+func (e *CommandPaletteTool) runCommand(m *Editor, cmd *Command, args []string) {
+	if err := cmd.Exec(m, args); err != nil {
+		e.err = err.Error()
+		return
+	}
+	m.ClearModalTool()
+}
+
+func (e *CommandPaletteTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
+	r := adraw.Area{
+		Width:  min(70, w-2),
+		Height: PALETTE_MAX_RESULTS + 3,
+	}
+	r.X = x + (w-r.Width)/2
+	r.Y = y + max(1, (h-r.Height)/3)
+	bb := adraw.Area{
+		X:      r.X - 1,
+		Y:      r.Y - 1,
+		Width:  r.Width + 2,
+		Height: r.Height + 2,
+	}
+	BorderBox(p, bb, tcell.StyleDefault)
+	FillRegion(p, r.X, r.Y, r.Width, r.Height, ' ', tcell.StyleDefault)
+	crop := &CropPainter{p: p, area: r}
+
+	SetString(crop, r.X, r.Y, "> ", tcell.StyleDefault)
+	e.input.Draw(crop, r.X+2, r.Y, r.Width-2, 1, lag)
+
+	if e.err != "" {
+		SetString(crop, r.X, r.Y+1, e.err, tcell.StyleDefault.Foreground(tcell.ColorRed))
+	}
+
+	// Keep the selection in view
+	first := max(0, e.selected-PALETTE_MAX_RESULTS+1)
+	for i := range PALETTE_MAX_RESULTS {
+		idx := first + i
+		if idx >= len(e.matches) {
+			break
+		}
+		item := e.matches[idx].item
+		st := tcell.StyleDefault
+		if idx == e.selected {
+			st = st.Reverse(true)
+			FillRegion(crop, r.X, r.Y+2+i, r.Width, 1, ' ', st)
+		}
+		keysWidth := Condition.StringWidth(item.keys)
+		line := &CropPainter{p: crop, area: adraw.Area{
+			X: r.X, Y: r.Y + 2 + i, Width: r.Width - keysWidth - 1, Height: 1,
+		}}
+		SetString(line, r.X, r.Y+2+i, fmt.Sprintf("%-28s %s", item.name, item.description), st)
+		if item.keys != "" {
+			SetString(crop, r.X+r.Width-keysWidth, r.Y+2+i, item.keys,
+				st.Foreground(tcell.ColorGray))
+		}
+	}
+
+	if len(e.matches) == 0 {
+		SetString(crop, r.X, r.Y+2, "no matches", tcell.StyleDefault.Foreground(tcell.ColorGray))
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCommandPaletteRunsSelection(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Type("x")
+	h.Click(pos(0, 0))
+
+	// "q" names a command, but the quit action is selected
+	h.Press("ctrl+k")
+	h.Type("q")
+	h.Press("down")
+	h.Press("down")
+	palette := h.Editor.currentModalTool.(*CommandPaletteTool)
+	if got := palette.matches[palette.selected].item.name; got != "quit" {
+		t.Fatalf("selected %q, want quit", got)
+	}
+	h.Press("enter")
+	if _, ok := h.Editor.currentModalTool.(*YesNoPromptTool); !ok {
+		t.Errorf("modal tool is %T, want the quit prompt", h.Editor.currentModalTool)
+	}
+}
+
+func TestCommandPaletteRunsCommandWithArgs(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Press("ctrl+k")
+	h.Type("fill 0 0 3 2 #")
+	h.Press("enter")
+	if h.Editor.hasModalTool {
+		t.Errorf("palette is still open")
+	}
+	h.AssertCanvas(`
+###
+###`)
+}
+
+func TestCommandPaletteCompletesCommand(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Press("ctrl+k")
+	h.Type("set radius")
+	h.Press("enter")
+	palette, ok := h.Editor.currentModalTool.(*CommandPaletteTool)
+	if !ok {
+		t.Fatalf("modal tool is %T, want the palette", h.Editor.currentModalTool)
+	}
+	if palette.input.Contents != "set radius " {
+		t.Errorf("input is %q, want the command ready for arguments", palette.input.Contents)
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query, target string
+		match         bool
+	}{
+		{"", "undo", true},
+		{"ud", "undo", true},
+		{"UNDO", "undo", true},
+		{"rsz", "resize canvas", true},
+		{"odnu", "undo", false},
+		{"undoo", "undo", false},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzyScore(tt.query, tt.target); ok != tt.match {
+			t.Errorf("fuzzyScore(%q, %q) matches: %v, want %v", tt.query, tt.target, ok, tt.match)
+		}
+	}
+
+	// Better matches come first: consecutive characters, starts of words, then shorter names
+	ranked := []struct {
+		query, better, worse string
+	}{
+		{"und", "undo", "unlocked"},
+		{"sa", "select all", "paste"},
+		{"quit", "quit", "force-quit"},
+	}
+	for _, tt := range ranked {
+		better, _ := fuzzyScore(tt.query, tt.better)
+		worse, _ := fuzzyScore(tt.query, tt.worse)
+		if better <= worse {
+			t.Errorf("%q scores %d for %q and %d for %q, want the first higher",
+				tt.query, better, tt.better, worse, tt.worse)
+		}
+	}
+}
+
+func TestFindCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		wantArgs []string
+	}{
+		{"resize canvas 80x24", "resize canvas", []string{"80x24"}},
+		{"resize 0 0 10 10", "resize", []string{"0", "0", "10", "10"}},
+		{"  set   fg   red ", "set fg", []string{"red"}},
+		{"  set   color ramp red blue ", "set color ramp", []string{"red", "blue"}},
+		{"set char #", "set char", []string{"#"}},
+		{"set radius", "set radius", []string{}},
+		{"q", "q", []string{}},
+		{"q!", "q!", []string{}},
+		{"qq", "", nil},
+		{"set", "", nil},
+		{"", "", nil},
+	}
+	for _, tt := range tests {
+		cmd, args, ok := FindCommand(tt.input)
+		if tt.name == "" {
+			if ok {
+				t.Errorf("FindCommand(%q) found %q, want nothing", tt.input, cmd.Name)
+			}
+			continue
+		}
+		if !ok || cmd.Name != tt.name || !slices.Equal(args, tt.wantArgs) {
+			t.Errorf("FindCommand(%q) = %v, %q, want %q, %q", tt.input, cmd, args, tt.name, tt.wantArgs)
+		}
+	}
+}
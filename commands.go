@@ -0,0 +1,790 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
+
+// A command that takes arguments, run by typing its name followed by the arguments, such as
+// "set fg red".
+type Command struct {
+	// One or more words, such as "resize canvas".
+	Name string
+	// Usage of the arguments, shown after the name.
+	Args        string
+	Description string
+	Run         func(c *Command, m *Editor, args []string) error
+}
+
+var commands []*Command
+
+func init() {
+	commands = []*Command{
+		{
+			Name:        "resize canvas",
+			Args:        "<width>x<height>",
+			Description: "resize the canvas, keeping the top-left corner",
+			Run:         cmdResizeCanvas,
+		},
+		{
+			Name:        "set fg",
+			Args:        "<color>",
+			Description: "set the foreground color",
+			Run:         cmdSetFg,
+		},
+		{
+			Name:        "set bg",
+			Args:        "<color>",
+			Description: "set the background color",
+			Run:         cmdSetBg,
+		},
+		{
+			Name:        "set char",
+			Args:        "<char>",
+			Description: "set the brush character",
+			Run:         cmdSetChar,
+		},
+		{
+			Name:        "set radius",
+			Args:        "<n>",
+			Description: "set the brush radius",
+			Run:         cmdSetRadius,
+		},
+		{
+			Name:        "set shape",
+			Args:        "<square|circle|diamond|custom>",
+			Description: "set the brush shape; custom takes the shape of the selection",
+			Run:         cmdSetShape,
+		},
+		{
+			Name:        "set ramp",
+			Args:        "<chars>",
+			Description: "set the characters of the pattern brush and gradients",
+			Run:         cmdSetRamp,
+		},
+		{
+			Name:        "set color ramp",
+			Args:        "<color>...",
+			Description: "set the colors of color gradients",
+			Run:         cmdSetColorRamp,
+		},
+		{
+			Name:        "gradient channel",
+			Args:        "<chars|colors|both>",
+			Description: "set which parts of the cells the gradient tool writes",
+			Run:         cmdGradientChannel,
+		},
+		{
+			Name:        "gradient dither",
+			Args:        "<on|off>",
+			Description: "set whether gradients are dithered",
+			Run:         cmdGradientDither,
+		},
+		{
+			Name:        "stamp anchor",
+			Args:        "<anchor>",
+			Description: "set the point of the clipboard under the cursor, such as center or top-left",
+			Run:         cmdStampAnchor,
+		},
+		{
+			Name:        "stamp opaque",
+			Args:        "<on|off>",
+			Description: "set whether stamping also writes blank cells",
+			Run:         cmdStampOpaque,
+		},
+		{
+			Name:        "stamp channel",
+			Args:        "<all|chars|colors>",
+			Description: "set which parts of the cells stamping writes",
+			Run:         cmdStampChannel,
+		},
+		{
+			Name:        "spray radius",
+			Args:        "<n>",
+			Description: "set the radius of the spray tool",
+			Run:         cmdSprayRadius,
+		},
+		{
+			Name:        "spray density",
+			Args:        "<percent>",
+			Description: "set the share of the spray circle painted by each burst",
+			Run:         cmdSprayDensity,
+		},
+		{
+			Name:        "spray seed",
+			Args:        "<n|random>",
+			Description: "start every spray stroke from a seed, or pick cells at random",
+			Run:         cmdSpraySeed,
+		},
+		{
+			Name:        "erase",
+			Args:        "<all|chars|colors>",
+			Description: "set which parts of the cells erasing clears",
+			Run:         cmdErase,
+		},
+		{
+			Name:        "symmetry",
+			Args:        "<off|horizontal|vertical|both|radial> [ways]",
+			Description: "mirror or rotate painting around the center of symmetry",
+			Run:         cmdSymmetry,
+		},
+		{
+			Name:        "symmetry center",
+			Args:        "[x y]",
+			Description: "move the center of symmetry, or back to the middle of the canvas",
+			Run:         cmdSymmetryCenter,
+		},
+		{
+			Name:        "palette load",
+			Args:        "<file>",
+			Description: "show colors in a palette from a .gpl or JSON file",
+			Run:         cmdPaletteLoad,
+		},
+		{
+			Name:        "palette save",
+			Args:        "<file>",
+			Description: "save the palette as a .gpl or JSON file",
+			Run:         cmdPaletteSave,
+		},
+		{
+			Name:        "palette reset",
+			Description: "show colors in the colors of the terminal",
+			Run:         cmdPaletteReset,
+		},
+		{
+			Name:        "palette set",
+			Args:        "<index> <color> [name]",
+			Description: "change an entry of the palette to a color name or #rrggbb",
+			Run:         cmdPaletteSet,
+		},
+		{
+			Name:        "resize",
+			Args:        "<width> <height>",
+			Description: "resize the canvas, keeping the top-left corner",
+			Run:         cmdResizeCanvas,
+		},
+		{
+			Name:        "fill",
+			Args:        "<x> <y> <width> <height> [char] [fg=<color>] [bg=<color>]",
+			Description: "fill a rectangle, using the brush for anything left out",
+			Run:         cmdFill,
+		},
+		{
+			Name:        "replace",
+			Args:        "[char] [fg=<color>] [bg=<color>] with [char] [fg=<color>] [bg=<color>]",
+			Description: "replace parts of the matching cells in the selection or canvas",
+			Run:         cmdReplace,
+		},
+		{
+			Name:        "select rect",
+			Args:        "<x> <y> <width> <height>",
+			Description: "select a rectangle",
+			Run:         cmdSelectRect,
+		},
+		{
+			Name:        "select all",
+			Description: "select the whole canvas",
+			Run:         cmdSelectAll,
+		},
+		{
+			Name:        "select none",
+			Description: "reset the selection",
+			Run:         cmdSelectNone,
+		},
+		{
+			Name:        "tile",
+			Description: "fill the selection with copies of the clipboard",
+			Run:         cmdTile,
+		},
+		{
+			Name:        "w",
+			Args:        "[file]",
+			Description: "save to the current or given file",
+			Run:         cmdWrite,
+		},
+		{
+			Name:        "wq",
+			Args:        "[file]",
+			Description: "save and quit",
+			Run:         cmdWrite,
+		},
+		{
+			Name:        "e",
+			Args:        "<file>",
+			Description: "load a file",
+			Run:         cmdEdit,
+		},
+		{
+			Name:        "e!",
+			Args:        "<file>",
+			Description: "load a file, discarding unsaved changes",
+			Run:         cmdEdit,
+		},
+		{
+			Name:        "q",
+			Description: "quit",
+			Run:         cmdQuit,
+		},
+		{
+			Name:        "q!",
+			Description: "quit without saving",
+			Run:         cmdQuit,
+		},
+	}
+}
+
+var errUnsavedChanges = errors.New("file has unsaved changes (add ! to override)")
+
+// Finds the command named at the start of the input, and splits off its arguments. The
+// command with the longest matching name wins, so "resize canvas 80x24" is not read as
+// "resize" with arguments.
+func FindCommand(input string) (*Command, []string, bool) {
+	fields := strings.Fields(input)
+	var best *Command
+	var bestLen int
+	for _, c := range commands {
+		name := strings.Fields(c.Name)
+		if len(name) > bestLen && len(fields) >= len(name) && slices.Equal(fields[:len(name)], name) {
+			best, bestLen = c, len(name)
+		}
+	}
+	if best == nil {
+		return nil, nil, false
+	}
+	return best, fields[bestLen:], true
+}
+
+// Runs the command with the given arguments.
+func (c *Command) Exec(m *Editor, args []string) error {
+	return c.Run(c, m, args)
+}
+
+func (c *Command) Usage() string {
+	if c.Args == "" {
+		return c.Name
+	}
+	return c.Name + " " + c.Args
+}
+
+// Reports whether the command cannot run without arguments.
+func (c *Command) NeedsArgs() bool {
+	return c.Args != "" && !strings.HasPrefix(c.Args, "[")
+}
+
+func (c *Command) errUsage() error {
+	return fmt.Errorf("usage: %s", c.Usage())
+}
+
+// Parses dimensions written as <width>x<height>.
+func parseDimensions(s string) (int, int, error) {
+	ws, hs, ok := strings.Cut(strings.ToLower(s), "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid dimensions %q (want <width>x<height>)", s)
+	}
+	w, err1 := strconv.Atoi(ws)
+	h, err2 := strconv.Atoi(hs)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("invalid dimensions %q (want <width>x<height>)", s)
+	}
+	if w <= 0 || h <= 0 {
+		return 0, 0, errors.New("width and height must be positive")
+	}
+	return w, h, nil
+}
+
+// Parses a brush character, which may also be written as "space".
+func parseBrushChar(s string) (byte, error) {
+	if s == "space" {
+		return ' ', nil
+	}
+	if len(s) != 1 || s[0] > unicode.MaxASCII || !unicode.IsGraphic(rune(s[0])) {
+		return 0, fmt.Errorf("invalid brush character %q", s)
+	}
+	return s[0], nil
+}
+
+// Parses a cell written as a character, fg=<color> and bg=<color>, in any order and each
+// optional. Parts that are left out are taken from def. Also returns the parts that were
+// given.
+func (m *Editor) parseCellArgs(args []string, def adraw.Cell) (adraw.Cell, adraw.LockMask, error) {
+	cell := def
+	fg, bg, _ := def.Style.Decompose()
+	var parts adraw.LockMask
+	for _, arg := range args {
+		var err error
+		if v, ok := strings.CutPrefix(arg, "fg="); ok {
+			fg, err = m.ParseColor(v)
+			parts |= adraw.LockMaskFg
+		} else if v, ok := strings.CutPrefix(arg, "bg="); ok {
+			bg, err = m.ParseColor(v)
+			parts |= adraw.LockMaskBg
+		} else {
+			cell.Value, err = parseBrushChar(arg)
+			parts |= adraw.LockMaskChar
+		}
+		if err != nil {
+			return cell, 0, err
+		}
+	}
+	cell.Style = tcell.StyleDefault.Foreground(fg).Background(bg)
+	return cell, parts, nil
+}
+
+// Parses a rectangle written as four numbers.
+func parseRect(args []string) (adraw.Area, error) {
+	var v [4]int
+	for i := range v {
+		n, err := strconv.Atoi(args[i])
+		if err != nil {
+			return adraw.Area{}, fmt.Errorf("%q is not a number", args[i])
+		}
+		v[i] = n
+	}
+	if v[2] <= 0 || v[3] <= 0 {
+		return adraw.Area{}, errors.New("width and height must be positive")
+	}
+	return adraw.Area{X: v[0], Y: v[1], Width: v[2], Height: v[3]}, nil
+}
+
+// Accepts the dimensions either as <width>x<height> or as two numbers.
+func cmdResizeCanvas(c *Command, m *Editor, args []string) error {
+	var w, h int
+	var err error
+	switch len(args) {
+	case 1:
+		w, h, err = parseDimensions(args[0])
+	case 2:
+		w, h, err = parseDimensions(args[0] + "x" + args[1])
+	default:
+		return c.errUsage()
+	}
+	if err != nil {
+		return err
+	}
+	m.ResizeCanvas(adraw.Area{Width: w, Height: h})
+	return nil
+}
+
+func cmdSetFg(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	color, err := m.ParseColor(args[0])
+	if err != nil {
+		return err
+	}
+	m.SetFg(color)
+	return nil
+}
+
+func cmdSetBg(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	color, err := m.ParseColor(args[0])
+	if err != nil {
+		return err
+	}
+	m.SetBg(color)
+	return nil
+}
+
+func cmdSetChar(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	char, err := parseBrushChar(args[0])
+	if err != nil {
+		return err
+	}
+	m.brushCharacter = char
+	return nil
+}
+
+func cmdSetRadius(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	r, err := strconv.Atoi(args[0])
+	if err != nil || r < 1 || r > m.config.MaxBrushRadius {
+		return fmt.Errorf("brush radius must be between 1 and %d", m.config.MaxBrushRadius)
+	}
+	m.brushRadius = r
+	return nil
+}
+
+func cmdSetShape(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	shape, err := adraw.ParseBrushShape(args[0])
+	if err != nil {
+		return err
+	}
+	return m.SetBrushShape(shape)
+}
+
+func cmdSetRamp(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	if err := ValidateRamp(args[0]); err != nil {
+		return err
+	}
+	m.ramp = []byte(args[0])
+	return nil
+}
+
+func cmdSetColorRamp(c *Command, m *Editor, args []string) error {
+	if len(args) == 0 {
+		return c.errUsage()
+	}
+	var colors []tcell.Color
+	for _, arg := range args {
+		color, err := m.ParseColor(arg)
+		if err != nil {
+			return err
+		}
+		colors = append(colors, color)
+	}
+	m.colorRamp = colors
+	return nil
+}
+
+func cmdFill(c *Command, m *Editor, args []string) error {
+	if len(args) < 4 {
+		return c.errUsage()
+	}
+	r, err := parseRect(args)
+	if err != nil {
+		return err
+	}
+
+	cell, _, err := m.parseCellArgs(args[4:], adraw.Cell{
+		Value: m.brushCharacter,
+		Style: tcell.StyleDefault.Foreground(m.fgColor).Background(m.bgColor),
+	})
+	if err != nil {
+		return err
+	}
+
+	m.Stage()
+	m.stagingCanvas.FillRegion(r.X, r.Y, r.Width, r.Height, cell, m.lockMask)
+	m.Commit()
+	return nil
+}
+
+func cmdSelectRect(c *Command, m *Editor, args []string) error {
+	if len(args) != 4 {
+		return c.errUsage()
+	}
+	r, err := parseRect(args)
+	if err != nil {
+		return err
+	}
+	m.Stage()
+	m.stagingCanvas.SetSelection(adraw.MakeGrid(r.Width, r.Height, true), adraw.Position{X: r.X, Y: r.Y})
+	m.Commit()
+	return nil
+}
+
+func cmdSelectAll(c *Command, m *Editor, args []string) error {
+	if len(args) != 0 {
+		return c.errUsage()
+	}
+	cc := m.CurrentCanvas()
+	return cmdSelectRect(c, m, []string{"0", "0", strconv.Itoa(cc.Data.Width), strconv.Itoa(cc.Data.Height)})
+}
+
+func cmdSelectNone(c *Command, m *Editor, args []string) error {
+	if len(args) != 0 {
+		return c.errUsage()
+	}
+	m.RunAction(action.Deselect)
+	return nil
+}
+
+// Saves to the given file, or to the current one. Quits afterwards if run as wq.
+func cmdWrite(c *Command, m *Editor, args []string) error {
+	path := strings.Join(args, " ")
+	if path == "" {
+		path = m.savedFile
+	}
+	if path == "" {
+		return errors.New("no file name")
+	}
+	// Save reports its own errors
+	if _, err := m.Save(path); err == nil && c.Name == "wq" {
+		m.app.WillQuit = true
+	}
+	return nil
+}
+
+func cmdEdit(c *Command, m *Editor, args []string) error {
+	if len(args) == 0 {
+		return c.errUsage()
+	}
+	if c.Name != "e!" && m.HasUnsavedChanges() {
+		return errUnsavedChanges
+	}
+	m.Load(strings.Join(args, " "))
+	return nil
+}
+
+func cmdQuit(c *Command, m *Editor, args []string) error {
+	if len(args) != 0 {
+		return c.errUsage()
+	}
+	if c.Name != "q!" && m.HasUnsavedChanges() {
+		return errUnsavedChanges
+	}
+	m.app.WillQuit = true
+	return nil
+}
+
+func cmdStampAnchor(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	anchor, err := ParseStampAnchor(args[0])
+	if err != nil {
+		return err
+	}
+	m.stamp.Anchor = anchor
+	return nil
+}
+
+func cmdStampOpaque(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	switch args[0] {
+	case "on":
+		m.stamp.Opaque = true
+	case "off":
+		m.stamp.Opaque = false
+	default:
+		return c.errUsage()
+	}
+	return nil
+}
+
+func cmdStampChannel(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	channel, err := ParseStampChannel(args[0])
+	if err != nil {
+		return err
+	}
+	m.stamp.Channel = channel
+	return nil
+}
+
+func cmdTile(c *Command, m *Editor, args []string) error {
+	if len(args) != 0 {
+		return c.errUsage()
+	}
+	m.TileFill()
+	return nil
+}
+
+func cmdGradientChannel(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	channel, err := ParseGradientChannel(args[0])
+	if err != nil {
+		return err
+	}
+	m.gradient.Channel = channel
+	return nil
+}
+
+func cmdGradientDither(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	switch args[0] {
+	case "on":
+		m.gradient.Dither = true
+	case "off":
+		m.gradient.Dither = false
+	default:
+		return c.errUsage()
+	}
+	return nil
+}
+
+func cmdSprayRadius(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	r, err := strconv.Atoi(args[0])
+	if err != nil || r < 1 || r > m.config.MaxBrushRadius {
+		return fmt.Errorf("spray radius must be between 1 and %d", m.config.MaxBrushRadius)
+	}
+	m.spray.Radius = r
+	return nil
+}
+
+func cmdSprayDensity(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	d, err := strconv.Atoi(strings.TrimSuffix(args[0], "%"))
+	if err != nil || d < 1 || d > 100 {
+		return errors.New("spray density must be between 1 and 100")
+	}
+	m.spray.Density = d
+	return nil
+}
+
+func cmdSpraySeed(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	if args[0] == "random" {
+		m.spray.Seed = 0
+		return nil
+	}
+	seed, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil || seed == 0 {
+		return errors.New("spray seed must be a positive number or random")
+	}
+	m.spray.Seed = seed
+	return nil
+}
+
+func cmdErase(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	mode, err := ParseEraseMode(args[0])
+	if err != nil {
+		return err
+	}
+	m.eraseMode = mode
+	return nil
+}
+
+func cmdSymmetry(c *Command, m *Editor, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return c.errUsage()
+	}
+	mode, err := adraw.ParseSymmetryMode(args[0])
+	if err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		if mode != adraw.SymmetryRadial {
+			return errors.New("only radial symmetry takes a number of ways")
+		}
+		ways, err := strconv.Atoi(args[1])
+		if err != nil || ways < 2 || ways > MAX_SYMMETRY_WAYS {
+			return fmt.Errorf("ways must be between 2 and %d", MAX_SYMMETRY_WAYS)
+		}
+		m.symmetry.Ways = ways
+	}
+	m.symmetry.Mode = mode
+	return nil
+}
+
+func cmdSymmetryCenter(c *Command, m *Editor, args []string) error {
+	cw, ch := m.CurrentCanvas().Data.Width, m.CurrentCanvas().Data.Height
+	switch len(args) {
+	case 0:
+		m.symmetry.CenterX, m.symmetry.CenterY = float64(cw-1)/2, float64(ch-1)/2
+	case 2:
+		var center [2]float64
+		for i, arg := range args {
+			v, err := strconv.ParseFloat(arg, 64)
+			if err != nil || v != math.Round(2*v)/2 {
+				return fmt.Errorf("invalid center %q (want a whole or half number)", arg)
+			}
+			center[i] = v
+		}
+		m.symmetry.CenterX, m.symmetry.CenterY = center[0], center[1]
+	default:
+		return c.errUsage()
+	}
+	return nil
+}
+
+func cmdReplace(c *Command, m *Editor, args []string) error {
+	i := slices.Index(args, "with")
+	if i == -1 || i == len(args)-1 {
+		return c.errUsage()
+	}
+	find, findParts, err := m.parseCellArgs(args[:i], adraw.Cell{})
+	if err != nil {
+		return err
+	}
+	with, parts, err := m.parseCellArgs(args[i+1:], adraw.Cell{})
+	if err != nil {
+		return err
+	}
+	m.FindReplace(adraw.CellPattern{Cell: find, Parts: findParts}, with, parts)
+	return nil
+}
+
+func cmdPaletteLoad(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	p, err := adraw.ReadPaletteFile(m.config.ResolvePath(args[0]))
+	if err != nil {
+		return err
+	}
+	m.SetPalette(p)
+	return nil
+}
+
+func cmdPaletteSave(c *Command, m *Editor, args []string) error {
+	if len(args) != 1 {
+		return c.errUsage()
+	}
+	return m.palette.WriteFile(m.config.ResolvePath(args[0]))
+}
+
+func cmdPaletteReset(c *Command, m *Editor, args []string) error {
+	if len(args) != 0 {
+		return c.errUsage()
+	}
+	m.SetPalette(adraw.DefaultPalette())
+	return nil
+}
+
+func cmdPaletteSet(c *Command, m *Editor, args []string) error {
+	if len(args) < 2 {
+		return c.errUsage()
+	}
+	i, err := strconv.Atoi(args[0])
+	if err != nil || i < 0 || i >= adraw.PaletteSize {
+		return fmt.Errorf("palette index must be between 0 and %d", adraw.PaletteSize-1)
+	}
+	color := tcell.GetColor(args[1])
+	if color == tcell.ColorDefault {
+		return fmt.Errorf("invalid color %q (want a color name or #rrggbb)", args[1])
+	}
+
+	p := m.palette.Clone()
+	for len(p.Entries) <= i {
+		p.Entries = append(p.Entries, p.Entry(len(p.Entries)))
+	}
+	p.Entries[i].Color = color
+	if len(args) > 2 {
+		p.Entries[i].Name = strings.Join(args[2:], " ")
+	}
+	m.SetPalette(p)
+	return nil
+}
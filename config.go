@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Keys of the color selector, in the order of the palette entries they pick.
+const PALETTE_KEYS = "12345678!@#$%^&*`"
+
+// Largest width and height of new canvases. Larger canvases take a long time to allocate
+// and draw at startup.
+const MAX_CANVAS_SIZE = 4096
+
+// Returns the path of a file in the configuration directory of the program. On Linux this
+// is $XDG_CONFIG_HOME/ascii-draw, falling back to ~/.config/ascii-draw.
+func ConfigPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ascii-draw", name), nil
+}
+
+// User preferences, stored as JSON in config.json in the configuration directory. Fields
+// missing from the file keep their default values.
+type Config struct {
+	// Dimensions of new canvases.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	BrushCharacter string `json:"brushCharacter"`
+	BrushRadius    int    `json:"brushRadius"`
+	MaxBrushRadius int    `json:"maxBrushRadius"`
+	// One of square, circle or diamond.
+	BrushShape string `json:"brushShape"`
+	// Characters cycled through by the pattern brush and used by character gradients, from
+	// lightest to darkest.
+	Ramp string `json:"ramp"`
+	// Colors used by color gradients, as palette entries.
+	ColorRamp []string `json:"colorRamp"`
+
+	// Distance from the center to the edge of the spray tool's circle.
+	SprayRadius int `json:"sprayRadius"`
+	// Share of the circle, in percent, that each burst of the spray tool paints.
+	SprayDensity int `json:"sprayDensity"`
+	// Seed that every spray stroke starts from, so that strokes can be reproduced. 0 picks a
+	// new random seed each session.
+	SpraySeed uint64 `json:"spraySeed"`
+
+	// Colors picked by each key of the color selector, see PALETTE_KEYS. Entries are names
+	// of the 16 ANSI colors, or "default".
+	Palette []string `json:"palette"`
+	// Palette file, in the GIMP .gpl format or JSON, that colors are shown in. Empty for the
+	// colors of the terminal.
+	PaletteFile string `json:"paletteFile"`
+
+	// How long notifications stay on screen, in seconds.
+	NotificationDuration float64 `json:"notificationDuration"`
+
+	// Directory that file prompts start in. Empty for the working directory.
+	SaveDirectory string `json:"saveDirectory"`
+	LogFile       string `json:"logFile"`
+
+	// How to reach the system clipboard: one of the CLIPBOARD_ constants.
+	Clipboard string `json:"clipboard"`
+	// Whether clipboard registers and history are kept in registers.json between sessions.
+	PersistRegisters bool `json:"persistRegisters"`
+}
+
+func DefaultConfig() *Config {
+	c := &Config{
+		Width:                80,
+		Height:               24,
+		BrushCharacter:       "#",
+		BrushRadius:          1,
+		MaxBrushRadius:       99,
+		BrushShape:           "square",
+		Ramp:                 " .:-=+*#%@",
+		ColorRamp:            []string{"black", "grey", "silver", "white"},
+		SprayRadius:          4,
+		SprayDensity:         10,
+		NotificationDuration: 10,
+		LogFile:              "logfile",
+		Clipboard:            CLIPBOARD_AUTO,
+	}
+	for i := range 16 {
+		c.Palette = append(c.Palette, (tcell.ColorValid + tcell.Color(i)).Name())
+	}
+	c.Palette = append(c.Palette, "default")
+	return c
+}
+
+// Parses a palette entry into one of the colors that drawings can store.
+func ParsePaletteColor(s string) (tcell.Color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "default" {
+		return tcell.ColorDefault, nil
+	}
+	if c, ok := tcell.ColorNames[s]; ok && c >= tcell.ColorValid && c < tcell.ColorValid+16 {
+		return c, nil
+	}
+	return tcell.ColorDefault, fmt.Errorf("invalid palette color %q (want one of the 16 ANSI color names or default)", s)
+}
+
+// Checks that a character ramp is usable by the pattern brush and gradients.
+func ValidateRamp(ramp string) error {
+	if ramp == "" {
+		return errors.New("ramp must not be empty")
+	}
+	for _, r := range ramp {
+		if r < 0x20 || r >= 0x7f {
+			return fmt.Errorf("ramp %q must only contain printable ASCII characters", ramp)
+		}
+	}
+	return nil
+}
+
+// Colors of the color ramp.
+func (c *Config) ColorRampColors() []tcell.Color {
+	colors := make([]tcell.Color, len(c.ColorRamp))
+	for i, s := range c.ColorRamp {
+		colors[i], _ = ParsePaletteColor(s)
+	}
+	return colors
+}
+
+// Colors of the palette, indexed like PALETTE_KEYS.
+func (c *Config) PaletteColors() []tcell.Color {
+	colors := make([]tcell.Color, len(PALETTE_KEYS))
+	for i := range colors {
+		colors[i] = tcell.ColorDefault
+		if i < len(c.Palette) {
+			colors[i], _ = ParsePaletteColor(c.Palette[i])
+		}
+	}
+	return colors
+}
+
+func (c *Config) NotificationTimeout() time.Duration {
+	return time.Duration(c.NotificationDuration * float64(time.Second))
+}
+
+// Checks that the configuration only contains usable values.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Width <= 0 || c.Height <= 0 {
+		errs = append(errs, errors.New("width and height must be positive"))
+	} else if c.Width > MAX_CANVAS_SIZE || c.Height > MAX_CANVAS_SIZE {
+		errs = append(errs, fmt.Errorf("width and height must be at most %d", MAX_CANVAS_SIZE))
+	}
+	if len(c.BrushCharacter) != 1 || c.BrushCharacter[0] < 0x20 || c.BrushCharacter[0] >= 0x7f {
+		errs = append(errs, fmt.Errorf("brush character %q must be a single printable ASCII character", c.BrushCharacter))
+	}
+	if c.MaxBrushRadius < 1 {
+		errs = append(errs, errors.New("max brush radius must be at least 1"))
+	}
+	if c.BrushRadius < 1 || c.BrushRadius > c.MaxBrushRadius {
+		errs = append(errs, fmt.Errorf("brush radius must be between 1 and %d", c.MaxBrushRadius))
+	}
+	if shape, err := adraw.ParseBrushShape(c.BrushShape); err != nil || shape == adraw.BrushCustom {
+		errs = append(errs, fmt.Errorf("brush shape %q must be square, circle or diamond", c.BrushShape))
+	}
+	if c.SprayRadius < 1 || c.SprayRadius > c.MaxBrushRadius {
+		errs = append(errs, fmt.Errorf("spray radius must be between 1 and %d", c.MaxBrushRadius))
+	}
+	if c.SprayDensity < 1 || c.SprayDensity > 100 {
+		errs = append(errs, errors.New("spray density must be between 1 and 100"))
+	}
+	if err := ValidateRamp(c.Ramp); err != nil {
+		errs = append(errs, err)
+	}
+	if len(c.ColorRamp) == 0 {
+		errs = append(errs, errors.New("color ramp must not be empty"))
+	}
+	for _, s := range c.ColorRamp {
+		if _, err := ParsePaletteColor(s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(c.Palette) != len(PALETTE_KEYS) {
+		errs = append(errs, fmt.Errorf("palette must have %d entries", len(PALETTE_KEYS)))
+	}
+	for _, s := range c.Palette {
+		if _, err := ParsePaletteColor(s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.NotificationDuration <= 0 {
+		errs = append(errs, errors.New("notification duration must be positive"))
+	}
+	if c.LogFile == "" {
+		errs = append(errs, errors.New("log file must not be empty"))
+	}
+	switch c.Clipboard {
+	case CLIPBOARD_AUTO, CLIPBOARD_OSC52, CLIPBOARD_HELPER:
+	default:
+		errs = append(errs, fmt.Errorf("clipboard %q must be one of %s, %s or %s",
+			c.Clipboard, CLIPBOARD_AUTO, CLIPBOARD_OSC52, CLIPBOARD_HELPER))
+	}
+	return errors.Join(errs...)
+}
+
+// Reads a configuration on top of the defaults. Invalid configurations are rejected as a
+// whole.
+func LoadConfig(r io.Reader) (*Config, error) {
+	c := DefaultConfig()
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return DefaultConfig(), err
+	}
+	if err := c.Validate(); err != nil {
+		return DefaultConfig(), err
+	}
+	return c, nil
+}
+
+// Reads config.json from the configuration directory. A missing file is not an error and
+// yields the defaults.
+func LoadConfigFile() (*Config, error) {
+	path, err := ConfigPath("config.json")
+	if err != nil {
+		return DefaultConfig(), err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return DefaultConfig(), nil
+	} else if err != nil {
+		return DefaultConfig(), err
+	}
+	defer f.Close()
+
+	c, err := LoadConfig(f)
+	if err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Writes the configuration to config.json, creating the configuration directory if needed.
+func (c *Config) Save() error {
+	path, err := ConfigPath("config.json")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Resolves a path entered in a file prompt against the save directory. A leading ~ in the
+// save directory stands for the home directory.
+func (c *Config) ResolvePath(s string) string {
+	if c.SaveDirectory == "" || s == "" || filepath.IsAbs(s) {
+		return s
+	}
+	dir := c.SaveDirectory
+	if rest, ok := strings.CutPrefix(dir, "~"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, rest)
+		}
+	}
+	return filepath.Join(dir, s)
+}
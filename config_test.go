@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		json    string
+		wantErr string
+	}{
+		{`{}`, ""},
+		{`{"width": 40, "brushShape": "circle", "clipboard": "osc52"}`, ""},
+		{`{"width": 0}`, "width and height must be positive"},
+		{`{"height": 5000}`, "width and height must be at most 4096"},
+		{`{"brushCharacter": "ab"}`, "brush character"},
+		{`{"brushRadius": 5, "maxBrushRadius": 4}`, "brush radius must be between 1 and 4"},
+		{`{"brushShape": "custom"}`, "brush shape"},
+		{`{"sprayDensity": 101}`, "spray density"},
+		{`{"ramp": ""}`, "ramp must not be empty"},
+		{`{"colorRamp": ["black", "mauve"]}`, `invalid palette color "mauve"`},
+		{`{"palette": ["red"]}`, "palette must have 17 entries"},
+		{`{"clipboard": "xclip"}`, `clipboard "xclip"`},
+		{`{"colour": "red"}`, "unknown field"},
+	}
+	for _, tt := range tests {
+		c, err := LoadConfig(strings.NewReader(tt.json))
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.json, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: got error %v, want %q", tt.json, err, tt.wantErr)
+		}
+		// Invalid configurations give the defaults
+		if c.Width != DefaultConfig().Width {
+			t.Errorf("%s: width is %d, want the default", tt.json, c.Width)
+		}
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		dir, path, want string
+	}{
+		{"", "x.adraw", "x.adraw"},
+		{"drawings", "", ""},
+		{"drawings", "x.adraw", filepath.Join("drawings", "x.adraw")},
+		{"drawings", "/tmp/x.adraw", "/tmp/x.adraw"},
+		{"/srv/art", "sub/x.adraw", "/srv/art/sub/x.adraw"},
+		{"~/art", "x.adraw", filepath.Join(home, "art", "x.adraw")},
+	}
+	for _, tt := range tests {
+		c := DefaultConfig()
+		c.SaveDirectory = tt.dir
+		if got := c.ResolvePath(tt.path); got != tt.want {
+			t.Errorf("ResolvePath(%q) in %q = %q, want %q", tt.path, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestConfigSaveRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	c := DefaultConfig()
+	c.Width, c.BrushCharacter = 33, "o"
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	path, err := ConfigPath("config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Width != 33 || got.BrushCharacter != "o" {
+		t.Errorf("loaded width %d and brush %q, want 33 and %q", got.Width, got.BrushCharacter, "o")
+	}
+}
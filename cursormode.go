@@ -0,0 +1,117 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// Distance moved by the cursor when shift is held.
+const CURSOR_FAST_STEP = 8
+
+// Handles the keys of keyboard cursor mode. The arrow keys and hjkl move the cursor, and
+// space presses or releases the pen. Every change is passed on to the tools as a mouse
+// event, so all of them work without a mouse: holding the pen down and moving paints,
+// draws lasso outlines and drags selections or resize handles.
+func (m *Editor) HandleCursorMode(event tcell.Event) bool {
+	if !m.cursorMode {
+		return false
+	}
+	ev, ok := event.(*tcell.EventKey)
+	if !ok || ev.Modifiers()&(tcell.ModCtrl|tcell.ModAlt|tcell.ModMeta) != 0 {
+		return false
+	}
+
+	step := 1
+	if ev.Modifiers()&tcell.ModShift != 0 {
+		step = CURSOR_FAST_STEP
+	}
+
+	var dx, dy int
+	switch ev.Key() {
+	case tcell.KeyLeft:
+		dx = -step
+	case tcell.KeyRight:
+		dx = step
+	case tcell.KeyUp:
+		dy = -step
+	case tcell.KeyDown:
+		dy = step
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'h':
+			dx = -1
+		case 'l':
+			dx = 1
+		case 'k':
+			dy = -1
+		case 'j':
+			dy = 1
+		case 'H':
+			dx = -CURSOR_FAST_STEP
+		case 'L':
+			dx = CURSOR_FAST_STEP
+		case 'K':
+			dy = -CURSOR_FAST_STEP
+		case 'J':
+			dy = CURSOR_FAST_STEP
+		case ' ':
+			m.penDown = !m.penDown
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+
+	m.cursorX = max(0, min(m.sw-1, m.cursorX+dx))
+	m.cursorY = max(0, min(m.sh-1, m.cursorY+dy))
+	m.handleSyntheticEvent(m.CursorEvent())
+	return true
+}
+
+// Mouse event for the current state of the keyboard cursor.
+func (m *Editor) CursorEvent() *tcell.EventMouse {
+	buttons := tcell.ButtonNone
+	if m.penDown {
+		buttons = tcell.Button1
+	}
+	return tcell.NewEventMouse(m.cursorX+m.sx, m.cursorY+m.sy, buttons, tcell.ModNone)
+}
+
+// Handles an event made up by the editor from the event being handled.
+func (m *Editor) handleSyntheticEvent(event tcell.Event) {
+	m.syntheticDepth++
+	defer func() {
+		m.syntheticDepth--
+	}()
+	m.HandleEvent(event)
+}
+
+// Turns keyboard cursor mode on or off. The pen is lifted when leaving cursor mode, so
+// that tools do not get stuck in the middle of a drag.
+func (m *Editor) SetCursorMode(enabled bool) {
+	if !enabled && m.penDown {
+		m.penDown = false
+		m.handleSyntheticEvent(m.CursorEvent())
+	}
+	m.cursorMode = enabled
+}
+
+func (m *Editor) DrawCursorMode(p Painter, x, y, w, h int) {
+	if !m.cursorMode {
+		return
+	}
+
+	status := "[cursor: pen up]"
+	if m.penDown {
+		status = "[cursor: pen dn]"
+	}
+	SetString(p, x+w-55, y, status, tcell.StyleDefault)
+
+	// The brush preview already marks the cursor for paint tools
+	if !m.IsPaintTool() {
+		cx, cy := m.cursorX+m.sx, m.cursorY+m.sy
+		_, st := p.GetContent(cx, cy)
+		_, _, attrs := st.Decompose()
+		p.SetStyle(cx, cy, st.Reverse(attrs&tcell.AttrReverse == 0))
+	}
+}
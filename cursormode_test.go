@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCursorModeMovement(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Mouse(pos(5, 5), 0)
+	h.Press("alt+k")
+
+	tests := []struct {
+		key  string
+		want [2]int
+	}{
+		{"l", [2]int{6, 5}},
+		{"right", [2]int{7, 5}},
+		{"k", [2]int{7, 4}},
+		{"up", [2]int{7, 3}},
+		{"h", [2]int{6, 3}},
+		{"j", [2]int{6, 4}},
+		{"L", [2]int{6 + CURSOR_FAST_STEP, 4}},
+		{"shift+left", [2]int{6, 4}},
+		{"J", [2]int{6, 4 + CURSOR_FAST_STEP}},
+	}
+	for _, tt := range tests {
+		h.Press(tt.key)
+		if got := h.Editor.cursorCanvasPosition(); got != pos(tt.want[0], tt.want[1]) {
+			t.Errorf("after %s the cursor is at %v, want %v", tt.key, got, tt.want)
+		}
+	}
+
+	// The cursor stays on the screen
+	m := h.Editor
+	for range m.sw {
+		h.Press("H")
+		h.Press("K")
+	}
+	if m.cursorX != 0 || m.cursorY != 0 {
+		t.Errorf("cursor is at screen position (%d, %d), want the top-left corner", m.cursorX, m.cursorY)
+	}
+}
+
+func TestCursorModePen(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("o")
+	h.Mouse(pos(1, 1), 0)
+	h.Press("alt+k")
+
+	// Moving with the pen up draws nothing
+	h.Type("ll")
+	h.AssertCanvas(``)
+
+	h.Type(" ljj")
+	if !h.Editor.penDown {
+		t.Fatal("space did not put the pen down")
+	}
+	h.AssertCanvas(`
+
+   oo
+    o
+    o`)
+
+	// Leaving cursor mode lifts the pen and finishes the stroke as one change
+	h.Press("alt+k")
+	if h.Editor.penDown {
+		t.Error("the pen is still down after leaving cursor mode")
+	}
+	h.Press("ctrl+z")
+	h.AssertCanvas(``)
+}
@@ -3,17 +3,17 @@ package main
 import (
 	"fmt"
 	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
 	"time"
 	"unicode"
 
+	"github.com/Fekinox/ascii-draw/adraw"
 	action "github.com/Fekinox/ascii-draw/internal"
 	"github.com/gdamore/tcell/v2"
 )
 
-const MAX_BRUSH_RADIUS int = 99
-const INIT_WIDTH = 80
-const INIT_HEIGHT = 24
-
 const EVENT_MONITORING = false
 
 type ColorPickState int
@@ -38,33 +38,6 @@ const (
 	ColorSelectBg
 )
 
-type LockMask int
-
-const (
-	// If set, makes painting operations ignore empty cells. Empty cells are cells where the
-	// character is the space character (` `) and where the background color is the terminal
-	// default.
-	LockMaskAlpha LockMask = 1 << iota
-	// If set, painting operations do not modify the character of a cell.
-	LockMaskChar
-	// If set, painting operations do not modify the foreground color of a cell.
-	LockMaskFg
-	// If set, painting operations do not modify the background color of a cell.
-	LockMaskBg
-)
-
-var colorMap = map[rune]int{
-	'1': 0, '!': 8,
-	'2': 1, '@': 9,
-	'3': 2, '#': 10,
-	'4': 3, '$': 11,
-	'5': 4, '%': 12,
-	'6': 5, '^': 13,
-	'7': 6, '&': 14,
-	'8': 7, '*': 15,
-	'`': 16,
-}
-
 type Editor struct {
 	app *App
 
@@ -76,11 +49,15 @@ type Editor struct {
 	cursorX int
 	cursorY int
 
+	// In cursor mode the cursor is moved with the keyboard, see HandleCursorMode.
+	cursorMode bool
+	penDown    bool
+
 	// Position of top-left corner of buffer
 	offsetX int
 	offsetY int
 
-	canvas *Buffer
+	canvas *adraw.Buffer
 
 	isPan      bool
 	panOriginX int
@@ -105,19 +82,37 @@ type Editor struct {
 	fgColor        tcell.Color
 	bgColor        tcell.Color
 	brushRadius    int
-	lockMask       LockMask
-
-	clipboard Grid[Cell]
+	brushShape     adraw.BrushShape
+	// Mask of the custom brush shape, taken from a selection.
+	customBrush adraw.Grid[bool]
+	// Whether the brush cycles through the ramp instead of painting brushCharacter.
+	patternBrush bool
+	ramp         []byte
+	colorRamp    []tcell.Color
+	gradient     GradientOptions
+	spray        SprayOptions
+	eraseMode    EraseMode
+	symmetry     adraw.Symmetry
+	lockMask     adraw.LockMask
+	// Source of the random choices of tools. Seeded from the user data, so that replays
+	// make the same choices.
+	rng *rand.Rand
+
+	clipboard adraw.Grid[adraw.Cell]
+	stamp     StampOptions
+	registers *ClipboardRegisters
+	// Register used by the next copy, cut or paste, or 0 for none.
+	pendingRegister rune
 
 	isStaging     bool
-	stagingCanvas *Buffer
+	stagingCanvas *adraw.Buffer
 
-	undoHistory    []*Buffer
+	undoHistory    []*adraw.Buffer
 	undoHistoryPos int
 	historyChanged bool
 
 	isPasting        bool
-	pendingPasteData []byte
+	pendingPasteData []rune
 
 	savedFile string
 	// Position of the currently saved editor state in the undo history
@@ -128,6 +123,38 @@ type Editor struct {
 	notification NotificationHandler
 
 	keymap map[KeyEvent]action.Action
+
+	recording  *macroRecording
+	macros     map[rune]Macro
+	macroDepth int
+	// Depth of events made up from other events, such as the mouse events of cursor mode.
+	// These are not recorded into macros, since replaying the events they came from makes
+	// them again.
+	syntheticDepth int
+
+	// Lines previously run in the command line, oldest first.
+	exHistory []string
+
+	config *Config
+	// Colors picked by the color selector, indexed like PALETTE_KEYS.
+	keyColors []tcell.Color
+	// Colors that the ANSI colors stored in the canvas are shown in.
+	palette *adraw.Palette
+	// Palette file from the configuration that palette was last loaded from.
+	paletteFile string
+	// Colors last used as the foreground or background, most recent first.
+	recentColors []tcell.Color
+
+	// State of the last drawn frame, used to only repaint the parts of the canvas that
+	// changed.
+	fullRedraw       bool
+	lastCanvas       *adraw.Buffer
+	lastCanvasOffset adraw.Position
+	overlayCells     []adraw.Position
+	// Region of the staging canvas painted since it was staged, which is repainted from the
+	// canvas if the change is rolled back.
+	stagedDamage    adraw.Area
+	isStagedDamaged bool
 }
 
 var (
@@ -135,14 +162,56 @@ var (
 )
 
 func Init(a *App, screen tcell.Screen) *Editor {
+	// The configuration is validated when it is loaded
+	brushShape, _ := adraw.ParseBrushShape(a.Config.BrushShape)
 	w := &Editor{
 		app:            a,
-		canvas:         MakeBuffer(INIT_WIDTH, INIT_HEIGHT),
-		brushCharacter: '#',
-		brushRadius:    1,
+		canvas:         adraw.MakeBuffer(a.Config.Width, a.Config.Height),
+		brushCharacter: a.Config.BrushCharacter[0],
+		brushRadius:    a.Config.BrushRadius,
+		brushShape:     brushShape,
 		appStartTime:   time.Now(),
 		notification:   &NotificationWidget{},
-		keymap:         defaultKeymap(),
+		keymap:         a.UserData.Keymap,
+		macros:         a.UserData.Macros,
+		registers:      a.UserData.Registers,
+		stamp:          StampOptions{Anchor: AnchorCenter},
+		spray: SprayOptions{
+			Radius:  a.Config.SprayRadius,
+			Density: a.Config.SprayDensity,
+			Seed:    a.Config.SpraySeed,
+		},
+		symmetry: adraw.Symmetry{
+			CenterX: float64(a.Config.Width-1) / 2,
+			CenterY: float64(a.Config.Height-1) / 2,
+			Ways:    DEFAULT_SYMMETRY_WAYS,
+		},
+		rng:     newRand(a.UserData.Seed),
+		palette: adraw.DefaultPalette(),
+	}
+	if len(w.registers.History) > 0 {
+		w.clipboard = w.registers.History[0]
+	}
+	w.ApplyConfig(a.Config)
+
+	if err := a.UserData.MacrosErr; err != nil {
+		a.Logger.Printf("Error loading macros: %v", err)
+		w.notification.PushNotification("Error loading macros", err.Error(), NotificationCritical)
+	}
+
+	if err := a.UserData.ConfigErr; err != nil {
+		a.Logger.Printf("Error loading config: %v", err)
+		w.notification.PushNotification("Error loading config", err.Error(), NotificationCritical)
+	}
+
+	if err := a.UserData.RegistersErr; err != nil {
+		a.Logger.Printf("Error loading registers: %v", err)
+		w.notification.PushNotification("Error loading registers", err.Error(), NotificationCritical)
+	}
+
+	if err := a.UserData.KeymapErr; err != nil {
+		a.Logger.Printf("Error loading keymap: %v", err)
+		w.notification.PushNotification("Error loading keymap", err.Error(), NotificationCritical)
 	}
 
 	w.ScreenResize(screen.Size())
@@ -150,6 +219,16 @@ func Init(a *App, screen tcell.Screen) *Editor {
 	w.ClearTool()
 
 	w.cursorX, w.cursorY = w.sw/2, w.sh/2
+
+	if !screen.HasMouse() {
+		w.SetCursorMode(true)
+		w.notification.PushNotification(
+			"No mouse detected",
+			"Starting in keyboard cursor mode: arrows or hjkl move, space lifts or presses the pen",
+			NotificationWarning,
+		)
+	}
+
 	a.Logger.Println("Successfully initialized program")
 	return w
 }
@@ -159,7 +238,7 @@ func defaultKeymap() map[KeyEvent]action.Action {
 		{Key: tcell.KeyCtrlQ}: action.Quit,
 		{Key: tcell.KeyCtrlQ,
 			Modifiers: tcell.ModAlt}: action.ForceQuit,
-		{Key: tcell.KeyCtrlH}: action.Help,
+		{Key: tcell.KeyF1}: action.Help,
 
 		{Key: tcell.KeyCtrlS}: action.Save,
 		{Key: tcell.KeyCtrlO}: action.Load,
@@ -179,6 +258,23 @@ func defaultKeymap() map[KeyEvent]action.Action {
 		{Key: tcell.KeyCtrlV}:        action.Paste,
 		RuneEvent(',', tcell.ModAlt): action.ClearSelection,
 		RuneEvent('.', tcell.ModAlt): action.FillSelection,
+		RuneEvent('c', tcell.ModAlt): action.SystemCopy,
+		RuneEvent('C', tcell.ModAlt): action.SystemCopyANSI,
+		RuneEvent('"', tcell.ModAlt): action.SelectRegister,
+		RuneEvent('v', tcell.ModAlt): action.RegisterPicker,
+		RuneEvent('o', tcell.ModAlt): action.StampOptions,
+		RuneEvent('t', tcell.ModAlt): action.TileFill,
+		RuneEvent('b', tcell.ModAlt): action.BrushShape,
+		RuneEvent('B', tcell.ModAlt): action.CustomBrush,
+		RuneEvent('p', tcell.ModAlt): action.PatternBrush,
+		RuneEvent('g', tcell.ModAlt): action.Gradient,
+		RuneEvent('a', tcell.ModAlt): action.Spray,
+		RuneEvent('e', tcell.ModAlt): action.Eraser,
+		RuneEvent('E', tcell.ModAlt): action.EraseMode,
+		RuneEvent('y', tcell.ModAlt): action.Symmetry,
+		RuneEvent('Y', tcell.ModAlt): action.SymmetryCenter,
+		RuneEvent('f', tcell.ModAlt): action.FindReplace,
+		RuneEvent('P', tcell.ModAlt): action.Palette,
 
 		{Key: tcell.KeyCtrlZ}: action.Undo,
 		{Key: tcell.KeyCtrlY}: action.Redo,
@@ -193,6 +289,13 @@ func defaultKeymap() map[KeyEvent]action.Action {
 		RuneEvent('4', tcell.ModAlt): action.BgLock,
 
 		RuneEvent('[', tcell.ModAlt): action.Resize,
+
+		RuneEvent('s', tcell.ModAlt): action.Settings,
+		{Key: tcell.KeyCtrlK}:        action.CommandPalette,
+		RuneEvent(':', tcell.ModAlt): action.CommandLine,
+		RuneEvent('k', tcell.ModAlt): action.CursorMode,
+		RuneEvent('q', tcell.ModAlt): action.RecordMacro,
+		RuneEvent('@', tcell.ModAlt): action.PlayMacro,
 	}
 }
 
@@ -206,8 +309,21 @@ func (m *Editor) HandleEvent(event tcell.Event) {
 		}
 	}
 
+	// Events are recorded into the current macro, except for the one that stops the
+	// recording. Events replayed from macros and synthetic events are not recorded again.
+	if rec := m.recording; rec != nil && m.macroDepth == 0 && m.syntheticDepth == 0 {
+		if r, ok := m.macroEvent(event); ok {
+			defer func() {
+				if m.recording == rec {
+					rec.events = append(rec.events, r)
+				}
+			}()
+		}
+	}
+
 	// Events are handled in the following order:
 	// - If a modal tool is active, it grabs all non-critical events.
+	// - In cursor mode, cursor movement keys are turned into mouse events.
 	// - Console resize events will automatically resize the canvas and scale the offset
 	// accordingly.
 	// - Any kind of panning event (either starting or stopping panning)
@@ -218,11 +334,20 @@ func (m *Editor) HandleEvent(event tcell.Event) {
 	// - If the current character pressed is a space or a printable character,
 	// then the brush character is set.
 	// - Finally it falls through to the current tool if all handlers fail.
+	// Pasted text arrives as key events, which must not reach the escape key handling, the
+	// shortcuts or the tools. Modal tools receive it as typed text instead.
+	if !m.hasModalTool {
+		if handled := m.HandlePaste(event); handled {
+			return
+		}
+	}
+
 	switch ev := event.(type) {
 	case *tcell.EventResize:
 		oldsw, oldsh := m.sw, m.sh
 		m.ScreenResize(ev.Size())
 		m.ScaleOffset(oldsw, oldsh, m.sw, m.sh)
+		m.fullRedraw = true
 		return
 	case *tcell.EventMouse:
 		cx, cy := ev.Position()
@@ -246,6 +371,10 @@ func (m *Editor) HandleEvent(event tcell.Event) {
 		return
 	}
 
+	if handled := m.HandleCursorMode(event); handled {
+		return
+	}
+
 	if handled := m.HandlePan(event); handled {
 		return
 	}
@@ -276,10 +405,6 @@ func (m *Editor) HandleEvent(event tcell.Event) {
 	}
 
 	m.currentTool.HandleEvent(m, event)
-
-	if handled := m.HandlePaste(event); handled {
-		return
-	}
 }
 
 func (m *Editor) HandlePaste(event tcell.Event) bool {
@@ -287,7 +412,7 @@ func (m *Editor) HandlePaste(event tcell.Event) bool {
 	case *tcell.EventPaste:
 		if ev.Start() {
 			m.isPasting = true
-			m.pendingPasteData = []byte{}
+			m.pendingPasteData = nil
 		} else if m.isPasting && ev.End() {
 			// set clipboard and return to stamp tool
 			m.SetClipboardFromPasteData()
@@ -299,22 +424,33 @@ func (m *Editor) HandlePaste(event tcell.Event) bool {
 		if !m.isPasting {
 			return false
 		}
-		switch ev.Key() {
-		case tcell.KeyRune:
-			r := ev.Rune()
-			if r > unicode.MaxASCII {
-				r = '?'
-			}
-			m.pendingPasteData = append(m.pendingPasteData, byte(r))
-		default:
-			m.pendingPasteData = append(m.pendingPasteData, '\n')
-		}
+		m.pendingPasteData = append(m.pendingPasteData, pastedText(ev)...)
 		return true
 	}
 
 	return false
 }
 
+// Text that the terminal sent for a key event during a paste. tcell splits escape sequences
+// that are not keys, such as the color codes of colored text, into an Alt+rune key followed
+// by plain runes, so those are turned back into the escape character and the rune.
+func pastedText(ev *tcell.EventKey) []rune {
+	var r rune
+	switch k := ev.Key(); {
+	case k == tcell.KeyRune:
+		r = ev.Rune()
+	case k < ' ' || k == tcell.KeyDEL:
+		r = rune(k)
+	default:
+		// Keys parsed from escape sequences cannot be turned back into text
+		return nil
+	}
+	if ev.Modifiers()&tcell.ModAlt != 0 {
+		return []rune{'\x1b', r}
+	}
+	return []rune{r}
+}
+
 func (m *Editor) HandlePan(event tcell.Event) bool {
 	switch ev := event.(type) {
 	case *tcell.EventMouse:
@@ -361,9 +497,9 @@ func (m *Editor) HandleColorPick(event tcell.Event) bool {
 				if ev.Buttons()&tcell.Button1 == 0 {
 					offsetY := m.cursorY - m.colorPickOriginY
 					if offsetY < -2 {
-						m.fgColor = m.hoverFg
+						m.SetFg(m.hoverFg)
 					} else if offsetY > 2 {
-						m.bgColor = m.hoverBg
+						m.SetBg(m.hoverBg)
 					} else {
 						m.brushCharacter = m.hoverChar
 					}
@@ -376,9 +512,9 @@ func (m *Editor) HandleColorPick(event tcell.Event) bool {
 			if m.colorPickState == ColorPickDrag {
 				offsetY := m.cursorX - m.colorPickOriginY
 				if offsetY < -2 {
-					m.fgColor = m.hoverFg
+					m.SetFg(m.hoverFg)
 				} else if offsetY > 2 {
-					m.bgColor = m.hoverBg
+					m.SetBg(m.hoverBg)
 				} else {
 					m.brushCharacter = m.hoverChar
 				}
@@ -399,12 +535,11 @@ func (m *Editor) HandleColorSelect(event tcell.Event) bool {
 
 	switch ev := event.(type) {
 	case *tcell.EventKey:
-		r := ev.Rune()
-		if newColor, ok := colorMap[r]; ok {
+		if i := strings.IndexRune(PALETTE_KEYS, ev.Rune()); i != -1 {
 			if m.colorSelectState == ColorSelectFg {
-				m.SetFgColor(newColor)
+				m.SetFgColor(i)
 			} else if m.colorSelectState == ColorSelectBg {
-				m.SetBgColor(newColor)
+				m.SetBgColor(i)
 			}
 		}
 		m.colorSelectState = ColorSelectNone
@@ -417,301 +552,439 @@ func (m *Editor) HandleShortcuts(event tcell.Event) bool {
 	switch ev := event.(type) {
 	case *tcell.EventKey:
 		if act, ok := m.keymap[ParseEvent(ev)]; ok {
-			switch act {
-			case action.CenterCanvas:
-				if m.isPan {
-					m.offsetX += m.cursorX - m.panOriginX
-					m.offsetY += m.cursorY - m.panOriginY
-					m.panOriginX = m.cursorX
-					m.panOriginY = m.cursorY
-				}
-				m.CenterCanvas()
-
-			case action.ForceQuit:
-				m.app.WillQuit = true
-
-			case action.Quit:
-				if m.HasUnsavedChanges() {
-					m.SetModalTool(&YesNoPromptTool{
-						prompt:    "File has unsaved changes, quit?",
-						yesString: "Save changes and quit",
-						yesAction: func() {
-							m.SetModalTool(MakePromptTool(
-								func(s string) {
-									if _, err := m.Save(s); err == nil {
-										m.app.WillQuit = true
-									}
-								},
-								"Save to ascii-draw file and quit",
-								"save path...",
-								m.savedFile,
-							))
-						},
-						noString: "Quit without saving",
-						noAction: func() {
-							m.app.WillQuit = true
+			m.RunAction(act)
+			return true
+		}
+	}
+	return false
+}
+
+// Performs an action, whether it was triggered by a key binding or chosen from the command
+// palette.
+func (m *Editor) RunAction(act action.Action) {
+	switch act {
+	case action.CenterCanvas:
+		if m.isPan {
+			m.offsetX += m.cursorX - m.panOriginX
+			m.offsetY += m.cursorY - m.panOriginY
+			m.panOriginX = m.cursorX
+			m.panOriginY = m.cursorY
+		}
+		m.CenterCanvas()
+
+	case action.ForceQuit:
+		m.app.WillQuit = true
+
+	case action.Quit:
+		if m.HasUnsavedChanges() {
+			m.SetModalTool(&YesNoPromptTool{
+				prompt:    "File has unsaved changes, quit?",
+				yesString: "Save changes and quit",
+				yesAction: func() {
+					m.SetModalTool(MakePromptTool(
+						func(s string) {
+							if _, err := m.Save(s); err == nil {
+								m.app.WillQuit = true
+							}
 						},
-					})
-				} else {
+						"Save to ascii-draw file and quit",
+						"save path...",
+						m.savedFile,
+					))
+				},
+				noString: "Quit without saving",
+				noAction: func() {
 					m.app.WillQuit = true
-				}
+				},
+			})
+		} else {
+			m.app.WillQuit = true
+		}
 
-			case action.Help:
-				m.SetModalTool(&HelpTool{})
-
-			case action.Save:
-				m.SetModalTool(MakePromptTool(
-					func(s string) {
-						_, _ = m.Save(s)
-					},
-					"Save to ascii-draw file",
-					"save path...",
-					m.savedFile,
-				))
-
-			case action.Load:
-				if m.HasUnsavedChanges() {
-					m.SetModalTool(&YesNoPromptTool{
-						prompt:    "File has unsaved changes, load file?",
-						yesString: "Save changes and load file",
-						yesAction: func() {
-							m.SetModalTool(MakePromptTool(
-								func(s string) {
-									if _, err := m.Save(s); err == nil {
-										m.SetModalTool(MakePromptTool(
-											m.Load,
-											"Load binary file",
-											"load path...",
-											"",
-										))
-									}
-								},
-								"Save to ascii-draw file",
-								"save path...",
-								m.savedFile,
-							))
-						},
-						noString: "Load file without saving current file",
-						noAction: func() {
-							m.SetModalTool(MakePromptTool(
-								m.Load,
-								"Load ascii-draw file",
-								"load path...",
-								"",
-							))
+	case action.Help:
+		m.SetModalTool(NewHelpTool(m.keymap))
+
+	case action.Settings:
+		m.SetModalTool(NewSettingsTool(m.config))
+
+	case action.CommandPalette:
+		m.SetModalTool(NewCommandPaletteTool(m.keymap))
+
+	case action.CommandLine:
+		m.SetModalTool(NewExLineTool(m))
+
+	case action.CursorMode:
+		m.SetCursorMode(!m.cursorMode)
+
+	case action.RecordMacro:
+		if m.recording != nil {
+			m.StopMacroRecording()
+			break
+		}
+		m.SetModalTool(&RegisterPromptTool{
+			prompt:    "Record macro into register",
+			registers: MACRO_REGISTERS,
+			onSelect:  m.StartMacroRecording,
+		})
+
+	case action.PlayMacro:
+		m.SetModalTool(&RegisterPromptTool{
+			prompt:    "Play macro from register",
+			registers: MACRO_REGISTERS,
+			onSelect: func(r rune) {
+				if err := m.PlayMacro(r); err != nil {
+					m.notification.PushNotification("Error", err.Error(), NotificationCritical)
+				}
+			},
+		})
+
+	case action.Save:
+		m.SetModalTool(MakePromptTool(
+			func(s string) {
+				_, _ = m.Save(s)
+			},
+			"Save to ascii-draw file",
+			"save path...",
+			m.savedFile,
+		))
+
+	case action.Load:
+		if m.HasUnsavedChanges() {
+			m.SetModalTool(&YesNoPromptTool{
+				prompt:    "File has unsaved changes, load file?",
+				yesString: "Save changes and load file",
+				yesAction: func() {
+					m.SetModalTool(MakePromptTool(
+						func(s string) {
+							if _, err := m.Save(s); err == nil {
+								m.SetModalTool(MakePromptTool(
+									m.Load,
+									"Load binary file",
+									"load path...",
+									"",
+								))
+							}
 						},
-					})
-				} else {
+						"Save to ascii-draw file",
+						"save path...",
+						m.savedFile,
+					))
+				},
+				noString: "Load file without saving current file",
+				noAction: func() {
 					m.SetModalTool(MakePromptTool(
 						m.Load,
 						"Load ascii-draw file",
 						"load path...",
 						"",
 					))
-				}
+				},
+			})
+		} else {
+			m.SetModalTool(MakePromptTool(
+				m.Load,
+				"Load ascii-draw file",
+				"load path...",
+				"",
+			))
+		}
 
-			case action.Export:
-				m.SetModalTool(MakePromptTool(
-					m.Export,
-					"Export to plaintext",
-					"export path...",
-					"",
-				))
-
-			case action.Import:
-				if m.HasUnsavedChanges() {
-					m.SetModalTool(&YesNoPromptTool{
-						prompt:    "File has unsaved changes, import file?",
-						yesString: "Save changes and import file",
-						yesAction: func() {
-							m.SetModalTool(MakePromptTool(
-								func(s string) {
-									if _, err := m.Save(s); err == nil {
-										m.SetModalTool(MakePromptTool(
-											m.Import,
-											"Import plaintext",
-											"import path...",
-											"",
-										))
-									}
-								},
-								"Save to ascii-draw file",
-								"save path...",
-								m.savedFile,
-							))
-						},
-						noString: "Import file without saving current file",
-						noAction: func() {
-							m.SetModalTool(MakePromptTool(
-								m.Import,
-								"Import plaintext",
-								"import path...",
-								"",
-							))
+	case action.Export:
+		m.SetModalTool(MakePromptTool(
+			m.Export,
+			"Export to plaintext",
+			"export path...",
+			"",
+		))
+
+	case action.Import:
+		if m.HasUnsavedChanges() {
+			m.SetModalTool(&YesNoPromptTool{
+				prompt:    "File has unsaved changes, import file?",
+				yesString: "Save changes and import file",
+				yesAction: func() {
+					m.SetModalTool(MakePromptTool(
+						func(s string) {
+							if _, err := m.Save(s); err == nil {
+								m.SetModalTool(MakePromptTool(
+									m.Import,
+									"Import plaintext",
+									"import path...",
+									"",
+								))
+							}
 						},
-					})
-				} else {
+						"Save to ascii-draw file",
+						"save path...",
+						m.savedFile,
+					))
+				},
+				noString: "Import file without saving current file",
+				noAction: func() {
 					m.SetModalTool(MakePromptTool(
 						m.Import,
 						"Import plaintext",
 						"import path...",
 						"",
 					))
-				}
+				},
+			})
+		} else {
+			m.SetModalTool(MakePromptTool(
+				m.Import,
+				"Import plaintext",
+				"import path...",
+				"",
+			))
+		}
 
-			case action.FgColorSelector:
-				if m.colorSelectState != ColorSelectFg {
-					m.colorSelectState = ColorSelectFg
-				} else {
-					m.colorSelectState = ColorSelectNone
-				}
+	case action.FgColorSelector:
+		if m.colorSelectState != ColorSelectFg {
+			m.colorSelectState = ColorSelectFg
+		} else {
+			m.colorSelectState = ColorSelectNone
+		}
 
-			case action.BgColorSelector:
-				if m.colorSelectState != ColorSelectBg {
-					m.colorSelectState = ColorSelectBg
-				} else {
-					m.colorSelectState = ColorSelectNone
-				}
+	case action.BgColorSelector:
+		if m.colorSelectState != ColorSelectBg {
+			m.colorSelectState = ColorSelectBg
+		} else {
+			m.colorSelectState = ColorSelectNone
+		}
 
-			case action.NewCanvas:
-				if m.HasUnsavedChanges() {
-					m.SetModalTool(&YesNoPromptTool{
-						prompt:    "File has unsaved changes, create new file?",
-						yesString: "Save changes and create new file",
-						yesAction: func() {
-							m.SetModalTool(MakePromptTool(
-								func(s string) {
-									if _, err := m.Save(s); err == nil {
-										m.canvas = MakeBuffer(INIT_WIDTH, INIT_HEIGHT)
-										m.savedFile = ""
-
-										m.Reset()
-										m.ClearHistory()
-
-										m.cursorX, m.cursorY = m.sw/2, m.sh/2
-									}
-								},
-								"Save to ascii-draw file",
-								"save path...",
-								m.savedFile,
-							))
-						},
-						noString: "Create new file without saving",
-						noAction: func() {
-							m.canvas = MakeBuffer(INIT_WIDTH, INIT_HEIGHT)
-							m.savedFile = ""
+	case action.NewCanvas:
+		if m.HasUnsavedChanges() {
+			m.SetModalTool(&YesNoPromptTool{
+				prompt:    "File has unsaved changes, create new file?",
+				yesString: "Save changes and create new file",
+				yesAction: func() {
+					m.SetModalTool(MakePromptTool(
+						func(s string) {
+							if _, err := m.Save(s); err == nil {
+								m.canvas = adraw.MakeBuffer(m.config.Width, m.config.Height)
+								m.savedFile = ""
 
-							m.Reset()
-							m.ClearHistory()
+								m.Reset()
+								m.ClearHistory()
 
-							m.cursorX, m.cursorY = m.sw/2, m.sh/2
+								m.cursorX, m.cursorY = m.sw/2, m.sh/2
+							}
 						},
-					})
-				} else {
-					m.canvas = MakeBuffer(INIT_WIDTH, INIT_HEIGHT)
+						"Save to ascii-draw file",
+						"save path...",
+						m.savedFile,
+					))
+				},
+				noString: "Create new file without saving",
+				noAction: func() {
+					m.canvas = adraw.MakeBuffer(m.config.Width, m.config.Height)
 					m.savedFile = ""
 
 					m.Reset()
 					m.ClearHistory()
 
 					m.cursorX, m.cursorY = m.sw/2, m.sh/2
-				}
+				},
+			})
+		} else {
+			m.canvas = adraw.MakeBuffer(m.config.Width, m.config.Height)
+			m.savedFile = ""
 
-			case action.Lasso:
-				m.SetTool(&LassoTool{})
-
-			case action.Translate:
-				m.SetTool(&TranslateTool{})
-
-			case action.Deselect:
-				m.Stage()
-				m.stagingCanvas.Deselect()
-				m.Commit()
-
-			case action.Copy:
-				m.SetClipboard()
-
-			case action.Cut:
-				m.SetClipboard()
-				m.Stage()
-				m.stagingCanvas.ClearSelection()
-				m.Commit()
-
-			case action.Paste:
-				m.SetTool(&StampTool{})
-
-			case action.Undo:
-				m.undoHistoryPos = max(0, m.undoHistoryPos-1)
-
-			case action.Redo:
-				m.undoHistoryPos = min(len(m.undoHistory), m.undoHistoryPos+1)
-
-			case action.IncreaseBrushRadius:
-				m.brushRadius = min(MAX_BRUSH_RADIUS, m.brushRadius+1)
-
-				// Decrease brush radius
-			case action.DecreaseBrushRadius:
-				m.brushRadius = max(1, m.brushRadius-1)
-
-				// Resize
-			case action.Resize:
-				t := &ResizeTool{}
-				t.SetDimsFromSelection(m.CurrentCanvas())
-				t.InitHandles()
-				m.SetTool(t)
-
-				// Toggle alpha lock
-			case action.AlphaLock:
-				m.lockMask ^= LockMaskAlpha
-
-				// Toggle character lock
-			case action.CharLock:
-				m.lockMask ^= LockMaskChar
-
-				// Toggle foreground color lock
-			case action.FgLock:
-				m.lockMask ^= LockMaskFg
-
-				// Toggle background color lock
-			case action.BgLock:
-				m.lockMask ^= LockMaskBg
-
-				// Clear selection
-			case action.ClearSelection:
-				m.Stage()
-				m.stagingCanvas.ClearSelection()
-				m.Commit()
-
-				// Fill selection with current brush
-			case action.FillSelection:
-				m.Stage()
-				c := Cell{
-					Value: m.brushCharacter,
-					Style: tcell.StyleDefault.Foreground(m.fgColor).Background(m.bgColor),
-				}
-				m.stagingCanvas.FillSelection(c, m.lockMask)
-				m.Commit()
-			}
-			return true
+			m.Reset()
+			m.ClearHistory()
+
+			m.cursorX, m.cursorY = m.sw/2, m.sh/2
+		}
+
+	case action.Brush:
+		m.ClearTool()
+
+	case action.Lasso:
+		m.SetTool(&LassoTool{})
+
+	case action.Translate:
+		m.SetTool(&TranslateTool{})
+
+	case action.Deselect:
+		m.Stage()
+		m.stagingCanvas.Deselect()
+		m.Commit()
+
+	case action.Copy:
+		m.SetClipboard()
+
+	case action.Cut:
+		if m.SetClipboard() {
+			m.Stage()
+			m.stagingCanvas.ClearSelection()
+			m.Commit()
+		}
+
+	case action.Paste:
+		if m.UsePendingRegister() {
+			m.SetTool(&StampTool{})
 		}
+
+	case action.SelectRegister:
+		m.SetModalTool(&RegisterPromptTool{
+			prompt:    "Register for the next copy, cut or paste:",
+			registers: MACRO_REGISTERS,
+			onSelect: func(r rune) {
+				m.pendingRegister = r
+			},
+		})
+
+	case action.RegisterPicker:
+		m.SetModalTool(NewRegisterPickerTool(m.registers))
+
+	case action.StampOptions:
+		m.SetModalTool(&StampOptionsTool{})
+
+	case action.TileFill:
+		m.TileFill()
+
+	case action.BrushShape:
+		m.SetBrushShape((m.brushShape + 1) % adraw.BrushCustom)
+
+	case action.CustomBrush:
+		if err := m.SetBrushShape(adraw.BrushCustom); err != nil {
+			m.notification.PushNotification("", "Nothing selected", NotificationWarning)
+		}
+
+	case action.PatternBrush:
+		m.patternBrush = !m.patternBrush
+
+	case action.Gradient:
+		m.SetTool(&GradientTool{})
+
+	case action.Spray:
+		m.SetTool(&SprayTool{})
+
+	case action.Eraser:
+		m.SetTool(&BrushTool{Eraser: true})
+
+	case action.EraseMode:
+		m.eraseMode = (m.eraseMode + 1) % EraseMode(len(eraseModeNames))
+
+	case action.Symmetry:
+		m.symmetry.Mode = (m.symmetry.Mode + 1) % (adraw.SymmetryRadial + 1)
+
+	case action.SymmetryCenter:
+		m.SetTool(&SymmetryTool{})
+
+	case action.FindReplace:
+		m.SetModalTool(NewFindReplaceTool())
+
+	case action.Palette:
+		m.SetModalTool(&PaletteTool{})
+
+	case action.SystemCopy, action.SystemCopyANSI:
+		m.SystemCopy(act == action.SystemCopyANSI)
+
+	case action.Undo:
+		m.undoHistoryPos = max(0, m.undoHistoryPos-1)
+
+	case action.Redo:
+		m.undoHistoryPos = min(len(m.undoHistory), m.undoHistoryPos+1)
+
+	case action.IncreaseBrushRadius:
+		if _, ok := m.currentTool.(*SprayTool); ok {
+			m.spray.Radius = min(m.config.MaxBrushRadius, m.spray.Radius+1)
+		} else {
+			m.brushRadius = min(m.config.MaxBrushRadius, m.brushRadius+1)
+		}
+
+		// Decrease brush radius
+	case action.DecreaseBrushRadius:
+		if _, ok := m.currentTool.(*SprayTool); ok {
+			m.spray.Radius = max(1, m.spray.Radius-1)
+		} else {
+			m.brushRadius = max(1, m.brushRadius-1)
+		}
+
+		// Resize
+	case action.Resize:
+		t := &ResizeTool{}
+		t.SetDimsFromSelection(m.CurrentCanvas())
+		t.InitHandles()
+		m.SetTool(t)
+
+		// Toggle alpha lock
+	case action.AlphaLock:
+		m.lockMask ^= adraw.LockMaskAlpha
+
+		// Toggle character lock
+	case action.CharLock:
+		m.lockMask ^= adraw.LockMaskChar
+
+		// Toggle foreground color lock
+	case action.FgLock:
+		m.lockMask ^= adraw.LockMaskFg
+
+		// Toggle background color lock
+	case action.BgLock:
+		m.lockMask ^= adraw.LockMaskBg
+
+		// Clear selection
+	case action.ClearSelection:
+		m.Stage()
+		m.stagingCanvas.ClearSelection()
+		m.Commit()
+
+		// Fill selection with current brush
+	case action.FillSelection:
+		m.Stage()
+		c := adraw.Cell{
+			Value: m.brushCharacter,
+			Style: tcell.StyleDefault.Foreground(m.fgColor).Background(m.bgColor),
+		}
+		m.stagingCanvas.FillSelection(c, m.lockMask)
+		m.Commit()
 	}
-	return false
 }
 
 func (m *Editor) Update() {
 	m.notification.Update()
 }
 
+func (m *Editor) Animating() bool {
+	// The color picker crosshairs are animated
+	return m.colorPickState == ColorPickHover || m.notification.Animating() || m.WantsTicks()
+}
+
+// Reports whether the current tool acts on tick events.
+func (m *Editor) WantsTicks() bool {
+	s, ok := m.currentTool.(*SprayTool)
+	return ok && s.isDragging && !m.hasModalTool
+}
+
 func (m *Editor) Draw(p Painter, x, y, w, h int, lag float64) {
-	// Draw surrounding box of screen
-	r := Area{
+	r := adraw.Area{
 		X:      x + 1,
 		Y:      y + 1,
 		Width:  w - 2,
 		Height: h - 2,
 	}
 
-	BorderBox(p, Area{
+	// Canvas offset for future drawing operations
+	canvasOffX, canvasOffY := m.offsetX, m.offsetY
+	if m.isPan {
+		canvasOffX += m.cursorX - m.panOriginX
+		canvasOffY += m.cursorY - m.panOriginY
+	}
+
+	m.DrawCanvas(p, r, canvasOffX, canvasOffY)
+
+	// Everything else is drawn on top of the canvas, and is tracked so that the cells
+	// underneath can be restored on the next frame.
+	overlay := &DamagePainter{p: p}
+	defer func() {
+		m.overlayCells = overlay.Touched
+	}()
+	p = overlay
+
+	// Draw surrounding box of screen
+	BorderBox(p, adraw.Area{
 		X:      r.X - 1,
 		Y:      r.Y - 1,
 		Width:  r.Width + 2,
@@ -721,18 +994,17 @@ func (m *Editor) Draw(p Painter, x, y, w, h int, lag float64) {
 	// Crop to edges of screen
 	crop := &CropPainter{
 		p:            p,
-		offsetBefore: Position{X: r.X, Y: r.Y},
+		offsetBefore: adraw.Position{X: r.X, Y: r.Y},
 		area:         r,
 	}
 
-	// Canvas offset for future drawing operations
-	canvasOffX, canvasOffY := m.offsetX, m.offsetY
-	if m.isPan {
-		canvasOffX += m.cursorX - m.panOriginX
-		canvasOffY += m.cursorY - m.panOriginY
-	}
-
-	m.DrawCanvas(crop, canvasOffX, canvasOffY)
+	BorderBox(crop, adraw.Area{
+		X:      canvasOffX - 1,
+		Y:      canvasOffY - 1,
+		Width:  m.CurrentCanvas().Data.Width + 2,
+		Height: m.CurrentCanvas().Data.Height + 2,
+	}, tcell.StyleDefault)
+	m.DrawSymmetryGuides(crop, canvasOffX, canvasOffY)
 
 	// If a tool is active, draw the given tool
 	if m.hasTool {
@@ -742,6 +1014,7 @@ func (m *Editor) Draw(p Painter, x, y, w, h int, lag float64) {
 	}
 
 	m.DrawStatusBar(p, x, y, w, h)
+	m.DrawCursorMode(p, x, y, w, h)
 
 	// color picker
 	if m.colorPickState == ColorPickHover {
@@ -790,19 +1063,20 @@ func (m *Editor) Draw(p Painter, x, y, w, h int, lag float64) {
 		)
 	} else if m.IsPaintTool() {
 		cx, cy := m.cursorX+m.sx, m.cursorY+m.sy
-		FillRegion(
-			p,
-			cx-m.brushRadius/2, cy-m.brushRadius/2,
-			m.brushRadius, m.brushRadius,
-			rune(m.brushCharacter),
-			tcell.StyleDefault.Foreground(m.fgColor).Background(m.bgColor),
-		)
-		p.SetByte(cx, cy, m.brushCharacter, tcell.StyleDefault.Foreground(m.fgColor).Background(m.bgColor))
+		b, _ := m.currentTool.(*BrushTool)
+		cell := m.brushPreviewCell(b.Eraser, 0)
+		st := m.palette.Style(cell.Style)
+		DrawBrush(p, m.BrushMask(), cx, cy, cell.Value, st)
+		p.SetByte(cx, cy, cell.Value, st)
 	}
 
 	// color selector
 	if m.colorSelectState != ColorSelectNone {
-		DrawColorSelector(p, x, y+1, m.colorSelectState)
+		shown := make([]tcell.Color, len(m.keyColors))
+		for i, c := range m.keyColors {
+			shown[i] = m.palette.Color(c)
+		}
+		DrawColorSelector(p, x, y+1, m.colorSelectState, shown)
 	}
 
 	// undo history
@@ -817,6 +1091,20 @@ func (m *Editor) Draw(p Painter, x, y, w, h int, lag float64) {
 
 	SetString(p, x+1, y+m.sh+m.sy, undoHistoryLine, tcell.StyleDefault)
 
+	if m.recording != nil {
+		SetCenteredString(
+			p, x+w/2, y+m.sh+m.sy,
+			fmt.Sprintf("recording @%c", m.recording.register),
+			tcell.StyleDefault.Foreground(tcell.ColorRed),
+		)
+	} else if m.pendingRegister != 0 {
+		SetCenteredString(
+			p, x+w/2, y+m.sh+m.sy,
+			fmt.Sprintf("register \"%c", m.pendingRegister),
+			tcell.StyleDefault.Foreground(tcell.ColorYellow),
+		)
+	}
+
 	// current filename
 	currentFile := m.savedFile
 	unsavedIndicator := ""
@@ -847,52 +1135,108 @@ func (m *Editor) Draw(p Painter, x, y, w, h int, lag float64) {
 
 func (m *Editor) DrawStatusBar(p Painter, x, y, w, h int) {
 	// color/char indicators
-	SetString(p, x+w-27, y, fmt.Sprintf("radius: %d", m.brushRadius), tcell.StyleDefault)
+	brush := fmt.Sprintf("radius: %d", m.brushRadius)
+	switch m.brushShape {
+	case adraw.BrushSquare:
+	case adraw.BrushCustom:
+		brush = "custom"
+	default:
+		brush = fmt.Sprintf("%s %d", m.brushShape, m.brushRadius)
+	}
+	SetString(p, x+w-27, y, brush, tcell.StyleDefault)
 	SetString(p, x+w-17, y, "char: ", tcell.StyleDefault)
 	p.SetByte(x+w-12, y, m.brushCharacter, tcell.StyleDefault)
 	SetString(p, x+w-10, y, "fg: ", tcell.StyleDefault)
-	DrawColorSymbolFG(p, x+w-7, y, m.fgColor)
+	DrawColorSymbolFG(p, x+w-7, y, m.palette.Color(m.fgColor))
 	SetString(p, x+w-5, y, "bg: ", tcell.StyleDefault)
-	DrawColorSymbolBG(p, x+w-2, y, m.bgColor)
+	DrawColorSymbolBG(p, x+w-2, y, m.palette.Color(m.bgColor))
 
 	// Lock mask
 	SetString(p, x+w-38, y, fmt.Sprintf("lock: ____"), tcell.StyleDefault)
-	if m.lockMask&LockMaskAlpha != 0 {
+	if m.lockMask&adraw.LockMaskAlpha != 0 {
 		p.SetByte(x+w-32, y, 'a', tcell.StyleDefault)
 	}
-	if m.lockMask&LockMaskChar != 0 {
+	if m.lockMask&adraw.LockMaskChar != 0 {
 		p.SetByte(x+w-31, y, 'c', tcell.StyleDefault)
 	}
-	if m.lockMask&LockMaskFg != 0 {
+	if m.lockMask&adraw.LockMaskFg != 0 {
 		p.SetByte(x+w-30, y, 'f', tcell.StyleDefault)
 	}
-	if m.lockMask&LockMaskBg != 0 {
+	if m.lockMask&adraw.LockMaskBg != 0 {
 		p.SetByte(x+w-29, y, 'g', tcell.StyleDefault)
 	}
 }
 
-func (m *Editor) DrawCanvas(p Painter, offX, offY int) {
-	// Rendering the canvas
+// Repaints the canvas layer of the screen area r. Only the cells that changed since the last
+// frame are repainted: the damaged region of the canvas and the cells that were covered by
+// overlays in the last frame. The whole area is repainted if the canvas was swapped out or
+// moved.
+func (m *Editor) DrawCanvas(p Painter, r adraw.Area, offX, offY int) {
 	curCanvas := m.CurrentCanvas()
-	curCanvas.RenderWith(p, offX, offY, true)
+	damage, isDamaged := curCanvas.TakeDamage()
+	if isDamaged && m.isStaging {
+		m.addStagedDamage(damage)
+	}
 
-	BorderBox(p, Area{
-		X:      offX - 1,
-		Y:      offY - 1,
-		Width:  curCanvas.Data.Width + 2,
-		Height: curCanvas.Data.Height + 2,
-	}, tcell.StyleDefault)
+	crop := &CropPainter{
+		p:            p,
+		offsetBefore: adraw.Position{X: r.X, Y: r.Y},
+		area:         r,
+	}
 
-	// selection mask
-	if curCanvas.activeSelection {
-		for y := range curCanvas.Data.Height {
-			for x := range curCanvas.Data.Width {
-				if curCanvas.SelectionMask.MustGet(x, y) {
-					xx, yy := x+offX, y+offY
-					_, s := p.GetContent(xx, yy)
-					p.SetStyle(xx, yy, s.Reverse(true))
-				}
+	offset := adraw.Position{X: offX, Y: offY}
+	if m.fullRedraw || curCanvas != m.lastCanvas || offset != m.lastCanvasOffset {
+		FillRegion(p, r.X, r.Y, r.Width, r.Height, ' ', tcell.StyleDefault)
+		m.DrawCanvasRegion(crop, offX, offY, adraw.Area{
+			X:      -offX,
+			Y:      -offY,
+			Width:  r.Width,
+			Height: r.Height,
+		})
+	} else {
+		for _, pos := range m.overlayCells {
+			p.SetByte(pos.X, pos.Y, ' ', tcell.StyleDefault)
+			m.DrawCanvasRegion(crop, offX, offY, adraw.Area{
+				X:      pos.X - r.X - offX,
+				Y:      pos.Y - r.Y - offY,
+				Width:  1,
+				Height: 1,
+			})
+		}
+		if isDamaged {
+			m.DrawCanvasRegion(crop, offX, offY, damage)
+		}
+	}
+
+	m.fullRedraw = false
+	m.lastCanvas = curCanvas
+	m.lastCanvasOffset = offset
+}
+
+// Paints the given region of the canvas, in canvas coordinates.
+func (m *Editor) DrawCanvasRegion(p Painter, offX, offY int, region adraw.Area) {
+	curCanvas := m.CurrentCanvas()
+	region, ok := region.Intersection(adraw.Area{
+		Width:  curCanvas.Data.Width,
+		Height: curCanvas.Data.Height,
+	})
+	if !ok {
+		return
+	}
+
+	for y := region.Top(); y < region.Bottom(); y++ {
+		for x := region.Left(); x < region.Right(); x++ {
+			c := curCanvas.Data.MustGet(x, y)
+			v, st := c.Value, c.Style
+			if v == 0 {
+				v = ' '
 			}
+			st = m.palette.Style(st)
+			// selection mask
+			if curCanvas.HasSelection() && curCanvas.SelectionMask.MustGet(x, y) {
+				st = st.Reverse(true)
+			}
+			p.SetByte(x+offX, y+offY, v, st)
 		}
 	}
 }
@@ -909,10 +1253,10 @@ func (m *Editor) ScaleOffset(oldsw, oldsh, newsw, newsh int) {
 	m.offsetY = int(float64(ocy)*sfy) + newsh/2
 }
 
-func (m *Editor) ResizeCanvas(newRect Area) {
+func (m *Editor) ResizeCanvas(newRect adraw.Area) {
 	curCanvas := m.CurrentCanvas()
 	m.Stage()
-	m.stagingCanvas = MakeBuffer(newRect.Width, newRect.Height)
+	m.stagingCanvas = adraw.MakeBuffer(newRect.Width, newRect.Height)
 	for y := range curCanvas.Data.Height {
 		for x := range curCanvas.Data.Width {
 			nx, ny := x-newRect.X, y-newRect.Y
@@ -923,6 +1267,8 @@ func (m *Editor) ResizeCanvas(newRect Area) {
 	m.Commit()
 	m.offsetX += newRect.X
 	m.offsetY += newRect.Y
+	m.symmetry.CenterX -= float64(newRect.X)
+	m.symmetry.CenterY -= float64(newRect.Y)
 	m.ClearTool()
 	m.ClearModalTool()
 }
@@ -936,12 +1282,14 @@ func (m *Editor) CenterCanvas() {
 
 func (m *Editor) SetTool(tool Tool) {
 	m.Rollback()
+	m.penDown = false
 	m.hasTool = true
 	m.currentTool = tool
 }
 
 func (m *Editor) ClearTool() {
 	m.Rollback()
+	m.penDown = false
 	m.hasTool = true
 	m.currentTool = &BrushTool{}
 }
@@ -958,23 +1306,57 @@ func (m *Editor) ClearModalTool() {
 	m.currentModalTool = nil
 }
 
-func (m *Editor) SetFgColor(fg int) {
-	if fg == 16 {
-		m.fgColor = tcell.ColorDefault
-	} else {
-		m.fgColor = tcell.ColorValid + tcell.Color(fg)
+// Sets the foreground color to the color of a key of the color selector.
+func (m *Editor) SetFgColor(i int) {
+	m.SetFg(m.keyColors[i])
+}
+
+// Sets the background color to the color of a key of the color selector.
+func (m *Editor) SetBgColor(i int) {
+	m.SetBg(m.keyColors[i])
+}
+
+func (m *Editor) SetFg(c tcell.Color) {
+	m.fgColor = c
+	m.useColor(c)
+}
+
+func (m *Editor) SetBg(c tcell.Color) {
+	m.bgColor = c
+	m.useColor(c)
+}
+
+// Moves a color to the front of the recently used colors.
+func (m *Editor) useColor(c tcell.Color) {
+	if i := slices.Index(m.recentColors, c); i != -1 {
+		m.recentColors = slices.Delete(m.recentColors, i, i+1)
+	}
+	m.recentColors = slices.Insert(m.recentColors, 0, c)
+	if len(m.recentColors) > RECENT_COLORS {
+		m.recentColors = m.recentColors[:RECENT_COLORS]
 	}
 }
 
-func (m *Editor) SetBgColor(bg int) {
-	if bg == 16 {
-		m.bgColor = tcell.ColorDefault
-	} else {
-		m.bgColor = tcell.ColorValid + tcell.Color(bg)
+// Applies the preferences that take effect immediately. The canvas and brush defaults only
+// apply to new canvases and the next start.
+func (m *Editor) ApplyConfig(c *Config) {
+	m.config = c
+	m.keyColors = c.PaletteColors()
+	if c.PaletteFile != m.paletteFile {
+		m.paletteFile = c.PaletteFile
+		m.LoadPaletteFromConfig()
+	}
+	m.ramp = []byte(c.Ramp)
+	m.colorRamp = c.ColorRampColors()
+	m.brushRadius = min(m.brushRadius, c.MaxBrushRadius)
+	m.spray.Radius = min(m.spray.Radius, c.MaxBrushRadius)
+	if n, ok := m.notification.(*NotificationWidget); ok {
+		n.Duration = c.NotificationTimeout()
 	}
 }
 
 func (m *Editor) Export(s string) {
+	s = m.config.ResolvePath(s)
 	var msg string
 	var err error
 	defer func() {
@@ -987,7 +1369,7 @@ func (m *Editor) Export(s string) {
 		}
 	}()
 
-	if err1 := m.CurrentCanvas().ExportToFile(s); err1 != nil {
+	if err1 := m.writeFile(s, m.CurrentCanvas().ExportToFile); err1 != nil {
 		err = err1
 		return
 	}
@@ -997,6 +1379,7 @@ func (m *Editor) Export(s string) {
 }
 
 func (m *Editor) Import(s string) {
+	s = m.config.ResolvePath(s)
 	var msg string
 	var err error
 	defer func() {
@@ -1009,7 +1392,7 @@ func (m *Editor) Import(s string) {
 		}
 	}()
 
-	newCanvas := &Buffer{}
+	newCanvas := &adraw.Buffer{}
 
 	if err1 := newCanvas.ImportFromFile(s); err1 != nil {
 		err = err1
@@ -1032,6 +1415,8 @@ func (m *Editor) Import(s string) {
 // caller deal with them somehow? i guess the rub is that these processses are inherently
 // asynchronous, so i might need a special way to take care of that
 func (m *Editor) Save(s string) (msg string, err error) {
+	// The file is remembered as typed, since it is resolved again by every later save
+	path := m.config.ResolvePath(s)
 	defer func() {
 		m.ClearTool()
 		m.ClearModalTool()
@@ -1046,7 +1431,7 @@ func (m *Editor) Save(s string) (msg string, err error) {
 		panic(1)
 	}
 
-	if err1 := m.CurrentCanvas().SaveToFile(s); err1 != nil {
+	if err1 := m.writeFile(path, m.CurrentCanvas().SaveToFile); err1 != nil {
 		err = err1
 		return
 	}
@@ -1054,13 +1439,23 @@ func (m *Editor) Save(s string) (msg string, err error) {
 	m.savedUndoIndex = m.undoHistoryPos
 	m.historyChanged = false
 
-	msg = fmt.Sprintf("Successfully saved %s", s)
-	m.app.Logger.Printf("Successfully saved binary file %s", s)
+	msg = fmt.Sprintf("Successfully saved %s", path)
+	m.app.Logger.Printf("Successfully saved binary file %s", path)
 
 	return msg, err
 }
 
+// Writes a file with the given function, unless the app is in dry-run mode.
+func (m *Editor) writeFile(s string, write func(string) error) error {
+	if m.app.DryRun {
+		m.app.Logger.Printf("Dry run, not writing %s", s)
+		return nil
+	}
+	return write(s)
+}
+
 func (m *Editor) Load(s string) {
+	path := m.config.ResolvePath(s)
 	var msg string
 	var err error
 	defer func() {
@@ -1073,9 +1468,9 @@ func (m *Editor) Load(s string) {
 		}
 	}()
 
-	newCanvas := &Buffer{}
+	newCanvas := &adraw.Buffer{}
 
-	if err1 := newCanvas.LoadFromFile(s); err1 != nil {
+	if err1 := newCanvas.LoadFromFile(path); err1 != nil {
 		err = err1
 		return
 	}
@@ -1088,42 +1483,17 @@ func (m *Editor) Load(s string) {
 	m.savedUndoIndex = m.undoHistoryPos
 	m.historyChanged = false
 
-	msg = fmt.Sprintf("Successfully loaded %s", s)
-	m.app.Logger.Printf("Successfully loaded binary file %s", s)
-}
-
-func (m *Editor) SetClipboard() {
-	m.clipboard = m.CurrentCanvas().CopySelection()
+	msg = fmt.Sprintf("Successfully loaded %s", path)
+	m.app.Logger.Printf("Successfully loaded binary file %s", path)
 }
 
+// Sets the clipboard to the pasted text. Color codes in the text are kept, and text without
+// them takes the current colors.
 func (m *Editor) SetClipboardFromPasteData() {
-	var width, height int
-	var w = 0
-	for _, c := range m.pendingPasteData {
-		if c == '\n' {
-			height++
-			w = 0
-		} else {
-			w++
-			width = max(width, w)
-		}
-	}
-	clip := MakeGrid(width+1, height+1, Cell{Value: ' '})
-
-	var x, y int
-	for _, c := range m.pendingPasteData {
-		if c == '\n' {
-			x, y = 0, y+1
-		} else {
-			clip.Set(x, y, Cell{
-				Value: c,
-				Style: tcell.StyleDefault.Foreground(m.fgColor).Background(m.bgColor),
-			})
-			x++
-		}
-	}
-
-	m.clipboard = clip
+	m.clipboard = adraw.ParseANSI(
+		string(m.pendingPasteData),
+		tcell.StyleDefault.Foreground(m.fgColor).Background(m.bgColor),
+	)
 }
 
 func (m *Editor) Stage() {
@@ -1134,6 +1504,17 @@ func (m *Editor) Stage() {
 	curCanvas := m.CurrentCanvas()
 	m.isStaging = true
 	m.stagingCanvas = curCanvas.Clone()
+	m.stagedDamage, m.isStagedDamaged = adraw.Area{}, false
+	m.stagingCanvas.Symmetry = m.symmetry
+
+	// The staging canvas starts out identical to the canvas on screen, and takes over the
+	// parts of it that still need to be repainted
+	if m.lastCanvas == curCanvas {
+		m.lastCanvas = m.stagingCanvas
+		if damage, ok := curCanvas.TakeDamage(); ok {
+			m.stagingCanvas.Damage(damage.X, damage.Y, damage.Width, damage.Height)
+		}
+	}
 }
 
 func (m *Editor) Commit() {
@@ -1147,6 +1528,12 @@ func (m *Editor) Commit() {
 	}
 
 	if curCanvas.Equal(m.stagingCanvas) {
+		if m.lastCanvas == m.stagingCanvas {
+			if damage, ok := m.stagingCanvas.TakeDamage(); ok {
+				curCanvas.Damage(damage.X, damage.Y, damage.Width, damage.Height)
+			}
+			m.lastCanvas = curCanvas
+		}
 		m.isStaging = false
 		m.stagingCanvas = nil
 		return
@@ -1173,11 +1560,34 @@ func (m *Editor) Rollback() {
 	if !m.isStaging {
 		return
 	}
+	staged := m.stagingCanvas
 	m.isStaging = false
 	m.stagingCanvas = nil
+
+	// Only the cells painted on the staging canvas differ from the canvas, so tools that
+	// start over on every drag event do not cause a full repaint
+	if m.lastCanvas == staged {
+		curCanvas := m.CurrentCanvas()
+		m.lastCanvas = curCanvas
+		if damage, ok := staged.TakeDamage(); ok {
+			m.addStagedDamage(damage)
+		}
+		if m.isStagedDamaged {
+			d := m.stagedDamage
+			curCanvas.Damage(d.X, d.Y, d.Width, d.Height)
+		}
+	}
+}
+
+func (m *Editor) addStagedDamage(damage adraw.Area) {
+	if m.isStagedDamaged {
+		m.stagedDamage = m.stagedDamage.Union(damage)
+	} else {
+		m.stagedDamage, m.isStagedDamaged = damage, true
+	}
 }
 
-func (m *Editor) CurrentCanvas() *Buffer {
+func (m *Editor) CurrentCanvas() *adraw.Buffer {
 	if m.isStaging {
 		return m.stagingCanvas
 	} else if m.undoHistoryPos < len(m.undoHistory) {
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+)
+
+func pos(x, y int) adraw.Position {
+	return adraw.Position{X: x, Y: y}
+}
+
+func TestBrushStroke(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("x")
+	h.Drag(pos(2, 1), pos(6, 1), pos(6, 3))
+
+	h.AssertCanvas(`
+
+  xxxxx
+      x
+      x`)
+
+	x, y := h.ScreenPos(pos(2, 1))
+	h.AssertScreenText(x, y, "xxxxx")
+}
+
+func TestBrushIsClippedToCanvas(t *testing.T) {
+	h := NewHarness(t, 5, 2)
+	h.Type("o")
+	h.Drag(pos(-3, 0), pos(8, 0))
+
+	h.AssertCanvas(`ooooo`)
+	h.AssertCanvasSize(5, 2)
+}
+
+func TestBrushShapes(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.RunExLine("set radius 3")
+	h.Press("alt+b")
+	h.Type("o")
+	h.Click(pos(2, 1))
+	h.Press("alt+b")
+	h.Type("d")
+	h.RunExLine("set radius 5")
+	h.Click(pos(9, 2))
+
+	h.AssertCanvas(`
+  o      d
+ ooo    ddd
+  o    ddddd
+        ddd
+         d`)
+
+	// The preview under the cursor has the same shape
+	x, y := h.ScreenPos(pos(9, 7))
+	h.Mouse(pos(9, 7), 0)
+	h.AssertScreenText(x-2, y-1, " ddd ")
+	h.AssertScreenText(x-2, y, "ddddd")
+}
+
+func TestCustomBrushLine(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	// Nothing selected yet
+	h.Press("alt+B")
+	h.Press("ctrl+r")
+	h.Drag(pos(0, 0), pos(1, 0), pos(1, 1))
+	h.Press("alt+B")
+	h.Press("ctrl+a")
+	h.Press("esc")
+
+	h.Press("tab")
+	h.Type("*")
+	h.Drag(pos(4, 4), pos(8, 4))
+	h.AssertCanvas(`
+
+
+
+   ******
+    *****`)
+}
+
+func TestPatternBrush(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.RunExLine("set ramp abc")
+	h.Press("alt+p")
+	h.Drag(pos(0, 0), pos(6, 0))
+	h.Press("tab")
+	h.Drag(pos(0, 2), pos(4, 2))
+
+	h.AssertCanvas(`
+abcabca
+
+abcab`)
+}
+
+func TestUndoRedo(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("x")
+	h.Drag(pos(0, 0), pos(3, 0))
+	h.Type("y")
+	h.Click(pos(0, 2))
+
+	h.Press("ctrl+z")
+	h.AssertCanvas(`xxxx`)
+	h.Press("ctrl+z")
+	h.AssertCanvas(``)
+	h.Press("ctrl+y")
+	h.AssertCanvas(`xxxx`)
+	h.Press("ctrl+y")
+	h.AssertCanvas(`
+xxxx
+
+y`)
+
+	// A new change discards the changes that were undone
+	h.Press("ctrl+z")
+	h.Type("z")
+	h.Click(pos(1, 1))
+	h.Press("ctrl+y")
+	h.AssertCanvas(`
+xxxx
+ z`)
+}
+
+func TestLassoSelection(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Press("ctrl+r")
+	h.Drag(pos(2, 2), pos(6, 2), pos(6, 5), pos(2, 5))
+
+	b := h.Canvas()
+	if !b.HasSelection() {
+		t.Fatal("lasso did not select anything")
+	}
+	for _, p := range []adraw.Position{pos(2, 2), pos(4, 3), pos(6, 5)} {
+		if !b.SelectionMask.MustGet(p.X, p.Y) {
+			t.Errorf("%v is not selected", p)
+		}
+	}
+	for _, p := range []adraw.Position{pos(1, 2), pos(7, 3), pos(4, 6)} {
+		if b.SelectionMask.MustGet(p.X, p.Y) {
+			t.Errorf("%v is selected", p)
+		}
+	}
+
+	h.Press("ctrl+a")
+	if h.Canvas().HasSelection() {
+		t.Error("selection was not cleared")
+	}
+}
+
+func TestTranslateSelection(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("x")
+	h.Drag(pos(3, 3), pos(4, 3))
+	h.Type("o")
+	h.Click(pos(10, 3))
+
+	h.Press("ctrl+r")
+	h.Drag(pos(2, 2), pos(5, 2), pos(5, 4), pos(2, 4))
+	h.Press("ctrl+t")
+	h.Drag(pos(3, 3), pos(5, 4), pos(6, 5))
+
+	h.AssertCanvas(`
+
+
+
+          o
+
+      xx`)
+
+	// The move is a single change
+	h.Press("ctrl+z")
+	h.AssertCanvas(`
+
+
+
+   xx     o`)
+}
+
+func TestResizeCanvas(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("x")
+	h.Click(pos(1, 1))
+	h.Click(pos(15, 8))
+
+	h.Press("alt+[")
+	// Drag the bottom right handle, which sits outside the corner of the canvas
+	h.Drag(pos(23, 13), pos(13, 8))
+	h.Press("enter")
+
+	h.AssertCanvasSize(10, 5)
+	h.AssertCanvas(`
+
+ x`)
+
+	h.Press("ctrl+z")
+	h.AssertCanvasSize(20, 10)
+}
+
+func TestSaveLoad(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	path := filepath.Join(t.TempDir(), "drawing.adraw")
+
+	h.Type("x")
+	h.Drag(pos(0, 0), pos(2, 0))
+	h.Press("ctrl+s")
+	h.Type(path)
+	h.Press("enter")
+
+	if h.Editor.HasUnsavedChanges() {
+		t.Error("saving left unsaved changes")
+	}
+	saved, _, err := adraw.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Data.Width != 20 || saved.Data.Height != 10 || saved.Data.MustGet(1, 0).Value != 'x' {
+		t.Errorf("saved file does not match the canvas")
+	}
+
+	h.Press("ctrl+n")
+	h.AssertCanvas(``)
+
+	h.Press("ctrl+o")
+	h.Type(path)
+	h.Press("enter")
+	h.AssertCanvas(`xxx`)
+	h.AssertCanvasSize(20, 10)
+	if h.Editor.HasUnsavedChanges() {
+		t.Error("loading left unsaved changes")
+	}
+}
+
+func TestSaveTwiceToSaveDirectory(t *testing.T) {
+	h := NewHarness(t, 10, 2)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.Mkdir("drawings", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	h.Editor.config.SaveDirectory = "drawings"
+
+	h.Type("x")
+	h.Click(pos(0, 0))
+	h.Editor.RunExLine("w drawing.adraw")
+	h.Click(pos(1, 0))
+	h.Editor.RunExLine("w")
+
+	if h.Editor.HasUnsavedChanges() {
+		t.Error("saving again left unsaved changes")
+	}
+	saved, _, err := adraw.ReadFile(filepath.Join(dir, "drawings", "drawing.adraw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Data.MustGet(1, 0).Value != 'x' {
+		t.Error("the second save did not go to the same file")
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("x")
+	h.Click(pos(0, 0))
+
+	h.Press("ctrl+o")
+	h.Press("enter") // unsaved changes: load without saving
+	h.Type(filepath.Join(t.TempDir(), "missing.adraw"))
+	h.Press("enter")
+
+	h.AssertCanvas(`x`)
+}
+
+func TestColoredPaste(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Send(tcell.NewEventPaste(true))
+	// tcell delivers an unknown escape sequence as an Alt+rune key followed by runes
+	h.Send(tcell.NewEventKey(tcell.KeyRune, '[', tcell.ModAlt))
+	h.Type("31mab")
+	h.Send(tcell.NewEventKey(tcell.KeyEnter, '\r', tcell.ModNone))
+	h.Send(tcell.NewEventKey(tcell.KeyTab, '\t', tcell.ModNone))
+	h.Type("c")
+	h.Send(tcell.NewEventPaste(false))
+
+	if _, ok := h.Editor.currentTool.(*StampTool); !ok {
+		t.Fatalf("paste did not switch to the stamp tool")
+	}
+	clip := h.Editor.clipboard
+	if clip.Width != 9 || clip.Height != 2 {
+		t.Fatalf("clipboard is %d x %d, want 9 x 2", clip.Width, clip.Height)
+	}
+	a := clip.MustGet(0, 0)
+	if fg, _, _ := a.Style.Decompose(); a.Value != 'a' || fg != tcell.ColorMaroon {
+		t.Errorf("first cell is %q in %v, want 'a' in maroon", a.Value, fg)
+	}
+	if c := clip.MustGet(8, 1); c.Value != 'c' {
+		t.Errorf("tab was not expanded, cell (8, 1) is %q", c.Value)
+	}
+}
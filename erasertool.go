@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
+
+func init() {
+	RegisterTool(ToolInfo{
+		Name:        "eraser",
+		Action:      action.Eraser,
+		Description: "clear cells with the brush shape",
+		Gestures: []string{
+			"click and drag: erase",
+			"tab: toggle straight line mode",
+			"right click and drag with the brush or spray tool: erase",
+		},
+	})
+}
+
+// Mouse buttons that erase with the brush and spray tools, whichever of them the terminal
+// reports for the right button.
+const ERASE_BUTTONS = tcell.Button2 | tcell.Button3
+
+// Parts of the cells that erasing clears.
+type EraseMode int
+
+const (
+	EraseAll EraseMode = iota
+	EraseChars
+	EraseColors
+)
+
+var eraseModeNames = [...]string{"all", "chars", "colors"}
+
+func (e EraseMode) String() string {
+	return eraseModeNames[e]
+}
+
+func ParseEraseMode(s string) (EraseMode, error) {
+	idx := slices.Index(eraseModeNames[:], s)
+	if idx == -1 {
+		return EraseAll, fmt.Errorf("invalid erase mode %q (want all, chars or colors)", s)
+	}
+	return EraseMode(idx), nil
+}
+
+// Cell that erasing writes.
+var eraseCell = adraw.Cell{Value: ' '}
+
+// Lock mask for erasing. Only the alpha lock of the editor applies: the other locks would
+// keep the eraser from clearing anything.
+func (m *Editor) EraseLockMask() adraw.LockMask {
+	mask := m.lockMask & adraw.LockMaskAlpha
+	switch m.eraseMode {
+	case EraseChars:
+		mask |= adraw.LockMaskFg | adraw.LockMaskBg
+	case EraseColors:
+		mask |= adraw.LockMaskChar
+	}
+	return mask
+}
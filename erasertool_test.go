@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestRightClickErases(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("x")
+	h.Drag(pos(0, 0), pos(9, 0))
+	h.Drag(pos(0, 1), pos(9, 1))
+	h.RunExLine("select rect 0 0 20 1")
+	h.Press("esc")
+
+	h.RightDrag(pos(2, 0), pos(4, 0), pos(4, 1))
+	h.AssertCanvas(`
+xx   xxxxx
+xxxxxxxxxx`)
+
+	// The brush still paints with the left button afterwards
+	h.Click(pos(3, 0))
+	h.AssertCanvas(`
+xx x xxxxx
+xxxxxxxxxx`)
+}
+
+func TestEraserModes(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.RunExLine("set fg red")
+	h.RunExLine("set bg blue")
+	h.Type("x")
+	h.Drag(pos(0, 0), pos(5, 0))
+
+	h.Press("alt+e")
+	h.RunExLine("erase colors")
+	h.Click(pos(0, 0))
+	h.RunExLine("erase chars")
+	h.Click(pos(1, 0))
+	h.RunExLine("erase all")
+	h.Click(pos(2, 0))
+	h.AssertCanvas(`x  xxx`)
+
+	wantBg := []tcell.Color{tcell.ColorDefault, tcell.ColorBlue, tcell.ColorDefault, tcell.ColorBlue}
+	for x, want := range wantBg {
+		c := h.Canvas().Data.MustGet(x, 0)
+		if _, bg, _ := c.Style.Decompose(); bg != want {
+			t.Errorf("background at %d is %v, want %v", x, bg, want)
+		}
+	}
+	if fg, _, _ := h.Canvas().Data.MustGet(0, 0).Style.Decompose(); fg != tcell.ColorDefault {
+		t.Errorf("erasing colors left foreground %v", fg)
+	}
+}
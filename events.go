@@ -0,0 +1,93 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// A tcell event in a form that can be stored as JSON.
+type RecordedEvent struct {
+	// Milliseconds since the start of the recording.
+	Time int64  `json:"t"`
+	Type string `json:"type"`
+
+	Key  tcell.Key     `json:"key,omitempty"`
+	Rune rune          `json:"rune,omitempty"`
+	Mod  tcell.ModMask `json:"mod,omitempty"`
+
+	X       int              `json:"x,omitempty"`
+	Y       int              `json:"y,omitempty"`
+	Buttons tcell.ButtonMask `json:"buttons,omitempty"`
+
+	// Whether a paste event starts or ends the paste.
+	Start bool `json:"start,omitempty"`
+
+	Width  int `json:"w,omitempty"`
+	Height int `json:"h,omitempty"`
+}
+
+const (
+	RecordedKey    = "key"
+	RecordedMouse  = "mouse"
+	RecordedPaste  = "paste"
+	RecordedResize = "resize"
+	RecordedTick   = "tick"
+)
+
+// Sent by the main loop on every update tick while a tool asks for ticks. Ticks are events
+// so that they are recorded, and replays see them at the same points between other events.
+type EventTick struct {
+	tcell.EventTime
+}
+
+func NewEventTick() *EventTick {
+	ev := &EventTick{}
+	ev.SetEventNow()
+	return ev
+}
+
+// Converts an event for storage, with its time relative to start. Events other than key,
+// mouse, paste, resize and tick events are not recorded.
+func RecordEvent(event tcell.Event, start time.Time) (RecordedEvent, bool) {
+	r := RecordedEvent{Time: event.When().Sub(start).Milliseconds()}
+	switch ev := event.(type) {
+	case *tcell.EventKey:
+		r.Type = RecordedKey
+		r.Key, r.Rune, r.Mod = ev.Key(), ev.Rune(), ev.Modifiers()
+	case *tcell.EventMouse:
+		r.Type = RecordedMouse
+		r.X, r.Y = ev.Position()
+		r.Buttons, r.Mod = ev.Buttons(), ev.Modifiers()
+	case *tcell.EventPaste:
+		r.Type = RecordedPaste
+		r.Start = ev.Start()
+	case *tcell.EventResize:
+		r.Type = RecordedResize
+		r.Width, r.Height = ev.Size()
+	case *EventTick:
+		r.Type = RecordedTick
+	default:
+		return r, false
+	}
+	return r, true
+}
+
+// Recreates the event. tcell stamps events with the time they were created, so the editor
+// must not depend on event times for replays to be faithful.
+func (r RecordedEvent) Event() tcell.Event {
+	switch r.Type {
+	case RecordedKey:
+		return tcell.NewEventKey(r.Key, r.Rune, r.Mod)
+	case RecordedMouse:
+		return tcell.NewEventMouse(r.X, r.Y, r.Buttons, r.Mod)
+	case RecordedPaste:
+		return tcell.NewEventPaste(r.Start)
+	case RecordedResize:
+		return tcell.NewEventResize(r.Width, r.Height)
+	case RecordedTick:
+		return NewEventTick()
+	default:
+		return nil
+	}
+}
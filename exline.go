@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Maximum number of lines kept in the command line history.
+const EX_HISTORY_SIZE = 100
+
+// Modal tool for the : command line at the bottom of the screen. Runs a single command from
+// the command registry, reporting errors as notifications.
+type ExLineTool struct {
+	input TextWidget
+	// Position in the history while recalling lines. Equal to the length of the history
+	// when editing a new line.
+	historyPos int
+	// Line being typed before the history was recalled.
+	draft string
+}
+
+var (
+	_ Tool = &ExLineTool{}
+)
+
+func NewExLineTool(m *Editor) *ExLineTool {
+	t := &ExLineTool{
+		input:      TextWidget{Active: true},
+		historyPos: len(m.exHistory),
+	}
+	t.input.OnSubmit = func(s string) {
+		m.ClearModalTool()
+		m.RunExLine(s)
+	}
+	return t
+}
+
+func (e *ExLineTool) HandleEvent(m *Editor, event tcell.Event) {
+	ev, ok := event.(*tcell.EventKey)
+	if !ok {
+		return
+	}
+
+	switch ev.Key() {
+	case tcell.KeyUp:
+		if e.historyPos == 0 {
+			return
+		}
+		if e.historyPos == len(m.exHistory) {
+			e.draft = e.input.Contents
+		}
+		e.historyPos--
+		e.input.SetContents(m.exHistory[e.historyPos])
+	case tcell.KeyDown:
+		if e.historyPos == len(m.exHistory) {
+			return
+		}
+		e.historyPos++
+		if e.historyPos == len(m.exHistory) {
+			e.input.SetContents(e.draft)
+		} else {
+			e.input.SetContents(m.exHistory[e.historyPos])
+		}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		// Backspace on an empty line leaves the command line, like in vi
+		if e.input.Contents == "" {
+			m.ClearModalTool()
+			return
+		}
+		e.input.HandleEvent(ev)
+	default:
+		e.input.HandleEvent(ev)
+	}
+}
+
+func (e *ExLineTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
+	row := y + h - 1
+	FillRegion(p, x, row, w, 1, ' ', tcell.StyleDefault)
+	p.SetByte(x, row, ':', tcell.StyleDefault)
+	e.input.Draw(p, x+1, row, w-1, 1, lag)
+}
+
+// Runs a line of the command line and records it in the history.
+func (m *Editor) RunExLine(line string) {
+	line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), ":"))
+	if line == "" {
+		return
+	}
+	if n := len(m.exHistory); n == 0 || m.exHistory[n-1] != line {
+		m.exHistory = append(m.exHistory, line)
+		if len(m.exHistory) > EX_HISTORY_SIZE {
+			m.exHistory = m.exHistory[1:]
+		}
+	}
+
+	cmd, args, ok := FindCommand(line)
+	if !ok {
+		m.notification.PushNotification("Error", fmt.Sprintf("unknown command: %s", line), NotificationCritical)
+		return
+	}
+	if err := cmd.Exec(m, args); err != nil {
+		m.notification.PushNotification("Error", fmt.Sprintf("%s: %v", cmd.Name, err), NotificationCritical)
+	}
+}
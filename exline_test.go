@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestExLineHistory(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	for _, line := range []string{"set char a", ":set char b", "set char b", "  set char c  "} {
+		h.Press("alt+:")
+		h.Type(line)
+		h.Press("enter")
+	}
+	if want := []string{"set char a", "set char b", "set char c"}; !slices.Equal(h.Editor.exHistory, want) {
+		t.Errorf("history is %q, want %q", h.Editor.exHistory, want)
+	}
+	if got := h.Editor.brushCharacter; got != 'c' {
+		t.Errorf("brush character is %q, want 'c'", got)
+	}
+
+	h.Press("alt+:")
+	h.Type("fill")
+	line := h.Editor.currentModalTool.(*ExLineTool)
+	for _, tt := range []struct {
+		key, want string
+	}{
+		{"up", "set char c"},
+		{"up", "set char b"},
+		{"up", "set char a"},
+		// Stops at the oldest line
+		{"up", "set char a"},
+		{"down", "set char b"},
+		{"down", "set char c"},
+		// Comes back to the line being typed
+		{"down", "fill"},
+		{"down", "fill"},
+	} {
+		h.Press(tt.key)
+		if got := line.input.Contents; got != tt.want {
+			t.Errorf("after %s the line is %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	h.Press("up")
+	h.Press("up")
+	h.Press("enter")
+	if got := h.Editor.brushCharacter; got != 'b' {
+		t.Errorf("rerunning a line set the brush character to %q, want 'b'", got)
+	}
+	if n := len(h.Editor.exHistory); h.Editor.exHistory[n-1] != "set char b" {
+		t.Errorf("the rerun line is not the newest in the history %q", h.Editor.exHistory)
+	}
+}
+
+func TestExLineHistorySize(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	for i := range EX_HISTORY_SIZE + 5 {
+		h.Editor.RunExLine(fmt.Sprintf("set radius %d", i%3+1))
+	}
+	if got := len(h.Editor.exHistory); got != EX_HISTORY_SIZE {
+		t.Errorf("history has %d lines, want %d", got, EX_HISTORY_SIZE)
+	}
+}
+
+func TestExLineBackspace(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Press("alt+:")
+	h.Type("q")
+	h.Press("backspace")
+	if !h.Editor.hasModalTool {
+		t.Fatal("deleting the last character closed the command line")
+	}
+	h.Press("backspace")
+	if h.Editor.hasModalTool {
+		t.Error("backspace on an empty line did not close the command line")
+	}
+}
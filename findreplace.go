@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Parts of a cell that find and replace can match and write.
+const allCellParts = adraw.LockMaskChar | adraw.LockMaskFg | adraw.LockMaskBg
+
+// Writes the given parts of with to every cell matching the pattern, in the selection or in
+// the whole canvas, as a single change. Locked parts of the cells are kept. Returns the
+// number of cells that changed; nothing is added to the history if none did.
+func (m *Editor) FindReplace(find adraw.CellPattern, with adraw.Cell, parts adraw.LockMask) int {
+	m.Stage()
+	n := m.stagingCanvas.Replace(find, with, m.replaceKeep(parts))
+	if n == 0 {
+		m.Rollback()
+		return 0
+	}
+	m.Commit()
+	return n
+}
+
+// Parts of the cells that find and replace leaves alone when writing the given parts,
+// including the ones held by the locks.
+func (m *Editor) replaceKeep(parts adraw.LockMask) adraw.LockMask {
+	return allCellParts&^parts | m.lockMask
+}
+
+// Describes what find and replace works on, for messages.
+func (m *Editor) searchScope() string {
+	if m.CurrentCanvas().HasSelection() {
+		return "the selection"
+	}
+	return "the canvas"
+}
+
+const (
+	findChar = iota
+	findFg
+	findBg
+	replaceChar
+	replaceFg
+	replaceBg
+	findReplaceFields
+)
+
+// Modal dialog for find and replace. Each part of the pattern and the replacement has its own
+// field: an empty field matches anything, or leaves that part of the cells alone. This is not
+// built on PromptTool, which holds a single line and submits on Enter, since the dialog moves
+// between six fields with Tab and previews the result as they change.
+type FindReplaceTool struct {
+	fields   [findReplaceFields]TextWidget
+	selected int
+	err      string
+}
+
+var (
+	_ Tool = &FindReplaceTool{}
+)
+
+func NewFindReplaceTool() *FindReplaceTool {
+	t := &FindReplaceTool{}
+	for i := range t.fields {
+		t.fields[i].Hint = "any"
+		if i >= replaceChar {
+			t.fields[i].Hint = "keep"
+		}
+	}
+	t.fields[0].Active = true
+	return t
+}
+
+// Parses three fields into a cell and the parts of it that were filled in.
+func (e *FindReplaceTool) parseCell(m *Editor, first int) (adraw.Cell, adraw.LockMask, error) {
+	var args []string
+	for i, prefix := range []string{"", "fg=", "bg="} {
+		if s := e.fields[first+i].Contents; s != "" {
+			args = append(args, prefix+s)
+		}
+	}
+	return m.parseCellArgs(args, adraw.Cell{})
+}
+
+func (e *FindReplaceTool) pattern(m *Editor) (adraw.CellPattern, error) {
+	cell, parts, err := e.parseCell(m, findChar)
+	return adraw.CellPattern{Cell: cell, Parts: parts}, err
+}
+
+func (e *FindReplaceTool) selectField(i int) {
+	e.fields[e.selected].Active = false
+	e.selected = (i + findReplaceFields) % findReplaceFields
+	e.fields[e.selected].Active = true
+}
+
+func (e *FindReplaceTool) HandleEvent(m *Editor, event tcell.Event) {
+	ev, ok := event.(*tcell.EventKey)
+	if !ok {
+		return
+	}
+
+	switch ev.Key() {
+	case tcell.KeyTab, tcell.KeyDown:
+		e.selectField(e.selected + 1)
+	case tcell.KeyBacktab, tcell.KeyUp:
+		e.selectField(e.selected - 1)
+	case tcell.KeyEnter:
+		e.submit(m)
+	default:
+		e.fields[e.selected].HandleEvent(ev)
+		e.err = ""
+	}
+}
+
+func (e *FindReplaceTool) submit(m *Editor) {
+	find, err := e.pattern(m)
+	if err != nil {
+		e.err = err.Error()
+		return
+	}
+	with, parts, err := e.parseCell(m, replaceChar)
+	if err != nil {
+		e.err = err.Error()
+		return
+	}
+	if parts == 0 {
+		e.err = "nothing to replace with"
+		return
+	}
+
+	n := m.FindReplace(find, with, parts)
+	m.ClearModalTool()
+	m.notification.PushNotification(
+		"", fmt.Sprintf("Replaced %d cells in %s", n, m.searchScope()), NotificationNormal,
+	)
+}
+
+// Describes what Enter would do: the number of matching cells and, once a replacement is
+// filled in, how many of them it would change.
+func (e *FindReplaceTool) preview(m *Editor) (string, error) {
+	find, err := e.pattern(m)
+	if err != nil {
+		return "", err
+	}
+	canvas := m.CurrentCanvas()
+	status := fmt.Sprintf("%d matches in %s", canvas.CountMatches(find), m.searchScope())
+	if with, parts, err := e.parseCell(m, replaceChar); err == nil && parts != 0 {
+		n := canvas.CountReplacements(find, with, m.replaceKeep(parts))
+		status += fmt.Sprintf(", %d to replace", n)
+	}
+	return status, nil
+}
+
+func (e *FindReplaceTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
+	r := adraw.Area{
+		Width:  44,
+		Height: 7,
+	}
+	r.X = x + (w-r.Width)/2
+	r.Y = y + (h-r.Height)/2
+	bb := adraw.Area{
+		X:      r.X - 1,
+		Y:      r.Y - 1,
+		Width:  r.Width + 2,
+		Height: r.Height + 2,
+	}
+	BorderBox(p, bb, tcell.StyleDefault)
+	FillRegion(p, r.X, r.Y, r.Width, r.Height, ' ', tcell.StyleDefault)
+	SetCenteredString(p, r.X+r.Width/2, r.Y, "Find and replace", tcell.StyleDefault)
+
+	const labelWidth, fieldWidth = 9, 11
+	for i, heading := range []string{"char", "fg", "bg"} {
+		SetString(p, r.X+labelWidth+i*fieldWidth, r.Y+1, heading, tcell.StyleDefault)
+	}
+	for row, label := range []string{"find", "replace"} {
+		yy := r.Y + 2 + row
+		SetString(p, r.X, yy, label, tcell.StyleDefault)
+		for col := range 3 {
+			i := row*3 + col
+			field := adraw.Area{
+				X:      r.X + labelWidth + col*fieldWidth,
+				Y:      yy,
+				Width:  fieldWidth - 1,
+				Height: 1,
+			}
+			crop := &CropPainter{p: p, area: field}
+			switch {
+			case i == e.selected:
+				e.fields[i].Draw(crop, field.X, field.Y, field.Width, 1, lag)
+			case e.fields[i].Contents == "":
+				st := tcell.StyleDefault.Foreground(tcell.ColorGray)
+				SetString(crop, field.X, field.Y, e.fields[i].Hint, st)
+			default:
+				SetString(crop, field.X, field.Y, e.fields[i].Contents, tcell.StyleDefault)
+			}
+		}
+	}
+
+	status, err := e.preview(m)
+	st := tcell.StyleDefault
+	if e.err != "" || err != nil {
+		status, st = e.err, tcell.StyleDefault.Foreground(tcell.ColorRed)
+		if status == "" {
+			status = err.Error()
+		}
+	}
+	crop := &CropPainter{p: p, area: adraw.Area{X: r.X, Y: r.Y + 5, Width: r.Width, Height: 1}}
+	SetString(crop, r.X, r.Y+5, status, st)
+
+	footer := "tab: next field  enter: replace  esc: close"
+	SetString(p, r.X, r.Y+r.Height-1, footer, tcell.StyleDefault.Foreground(tcell.ColorGray))
+}
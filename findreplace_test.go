@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestReplaceCommand(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Type("*")
+	h.Drag(pos(0, 0), pos(3, 0))
+	h.Type("#")
+	h.Drag(pos(0, 1), pos(3, 1))
+
+	h.RunExLine("select rect 0 0 2 2")
+	h.RunExLine("replace * with +")
+	h.AssertCanvas(`
+++**
+####`)
+
+	h.RunExLine("select none")
+	h.RunExLine("replace # with fg=green")
+	c, _ := h.Canvas().Get(3, 1)
+	if fg, _, _ := c.Style.Decompose(); c.Value != '#' || fg != tcell.ColorGreen {
+		t.Errorf("replaced cell is %q in %v, want '#' in green", c.Value, fg)
+	}
+}
+
+func TestFindReplaceDialog(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Type("x")
+	h.Drag(pos(0, 0), pos(2, 0))
+	h.Click(pos(5, 2))
+
+	h.Press("alt+f")
+	h.Type("x")
+	h.Press("tab")
+	h.Press("tab")
+	h.Press("tab")
+	h.Type("o")
+	if got := h.Editor.currentModalTool.(*FindReplaceTool).selected; got != replaceChar {
+		t.Fatalf("selected field %d, want the replacement character", got)
+	}
+	h.Press("enter")
+	h.AssertCanvas(`
+ooo
+
+     o`)
+
+	// Every replaced cell comes back with one undo
+	h.Press("ctrl+z")
+	h.AssertCanvas(`
+xxx
+
+     x`)
+}
+
+func TestReplaceWithoutChanges(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Type("x")
+	h.Drag(pos(0, 0), pos(2, 0))
+	startPos := h.Editor.undoHistoryPos
+
+	// Nothing is added to the history when no cell changes
+	h.RunExLine("replace o with +")
+	h.RunExLine("replace x with x")
+	if got := h.Editor.undoHistoryPos; got != startPos {
+		t.Errorf("history moved from %d to %d without any changes", startPos, got)
+	}
+}
+
+func TestFindReplacePreview(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Type("x")
+	h.Drag(pos(0, 0), pos(2, 0))
+	h.Type("o")
+	h.Click(pos(5, 2))
+
+	h.Press("alt+f")
+	h.Type("x")
+	dialog := h.Editor.currentModalTool.(*FindReplaceTool)
+	if got, _ := dialog.preview(h.Editor); got != "3 matches in the canvas" {
+		t.Errorf("preview is %q before a replacement is filled in", got)
+	}
+	h.Press("tab")
+	h.Press("tab")
+	h.Press("tab")
+	h.Type("x")
+	if got, _ := dialog.preview(h.Editor); got != "3 matches in the canvas, 0 to replace" {
+		t.Errorf("preview is %q when replacing with the same character", got)
+	}
+}
+
+func TestFindReplaceKeepsLocks(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Type("x")
+	h.Drag(pos(0, 0), pos(2, 0))
+
+	// With the character locked, the dialog expects nothing to change
+	h.Press("alt+2")
+	h.Press("alt+f")
+	h.Type("x")
+	h.Press("tab")
+	h.Press("tab")
+	h.Press("tab")
+	h.Type("+")
+	dialog := h.Editor.currentModalTool.(*FindReplaceTool)
+	if got, _ := dialog.preview(h.Editor); got != "3 matches in the canvas, 0 to replace" {
+		t.Errorf("preview is %q with the character locked", got)
+	}
+	h.Press("esc")
+
+	// Only the color is replaced
+	h.RunExLine("replace x with + fg=red")
+	h.AssertCanvas(`xxx`)
+	c, _ := h.Canvas().Get(0, 0)
+	if fg, _, _ := c.Style.Decompose(); fg != tcell.ColorRed {
+		t.Errorf("replaced cell is in %v, want red", fg)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
+
+func init() {
+	RegisterTool(ToolInfo{
+		Name:        "gradient",
+		Action:      action.Gradient,
+		Description: "fill the selection with the character or color ramp",
+		Gestures: []string{
+			"click and drag: fill from the first to the last ramp step along the drag",
+			"tab: toggle dithering",
+			"right click does not erase; clear the selection with alt+, instead",
+		},
+	})
+}
+
+// Parts of the cells that the gradient tool writes.
+type GradientChannel int
+
+const (
+	GradientChars GradientChannel = iota
+	GradientColors
+	GradientBoth
+)
+
+var gradientChannelNames = [...]string{"chars", "colors", "both"}
+
+func (c GradientChannel) String() string {
+	return gradientChannelNames[c]
+}
+
+func ParseGradientChannel(s string) (GradientChannel, error) {
+	idx := slices.Index(gradientChannelNames[:], s)
+	if idx == -1 {
+		return GradientChars, fmt.Errorf("invalid channel %q (want chars, colors or both)", s)
+	}
+	return GradientChannel(idx), nil
+}
+
+type GradientOptions struct {
+	Channel GradientChannel
+	// Blend between neighboring ramp steps with ordered dithering.
+	Dither bool
+}
+
+func (o GradientOptions) String() string {
+	if o.Dither {
+		return o.Channel.String() + ", dithered"
+	}
+	return o.Channel.String()
+}
+
+type GradientTool struct {
+	isDragging bool
+	start      adraw.Position
+}
+
+var (
+	_ Tool = &GradientTool{}
+)
+
+// Fills the selection of the staging canvas with the gradient between two canvas positions.
+func (m *Editor) StageGradient(from, to adraw.Position) {
+	chars, colors := m.ramp, m.colorRamp
+	switch m.gradient.Channel {
+	case GradientChars:
+		colors = nil
+	case GradientColors:
+		chars = nil
+	}
+	m.Stage()
+	m.stagingCanvas.Gradient(from, to, chars, colors, m.gradient.Dither, m.lockMask)
+}
+
+func (g *GradientTool) HandleEvent(m *Editor, event tcell.Event) {
+	switch ev := event.(type) {
+	case *tcell.EventMouse:
+		p := m.cursorCanvasPosition()
+		if ev.Buttons()&tcell.Button1 != 0 {
+			if !g.isDragging {
+				g.isDragging = true
+				g.start = p
+			}
+			// Start over from the canvas, so that the gradient follows the drag
+			m.Rollback()
+			m.StageGradient(g.start, p)
+		} else if g.isDragging {
+			g.isDragging = false
+			m.Commit()
+		}
+	case *tcell.EventKey:
+		if ev.Key() == tcell.KeyTab {
+			m.gradient.Dither = !m.gradient.Dither
+		}
+	}
+}
+
+func (g *GradientTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
+	SetString(p, x+m.sx, y+m.sy-1, fmt.Sprintf("Gradient Tool (%s)", m.gradient), tcell.StyleDefault)
+	if !g.isDragging {
+		return
+	}
+	// Mark both ends of the drag
+	st := tcell.StyleDefault.Reverse(true)
+	p.SetByte(g.start.X+m.offsetX+m.sx, g.start.Y+m.offsetY+m.sy, 'o', st)
+	p.SetByte(m.cursorX+m.sx, m.cursorY+m.sy, 'x', st)
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestGradientTool(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.RunExLine("set ramp .:#")
+	h.RunExLine("select rect 2 1 6 2")
+	h.Press("alt+g")
+	h.Drag(pos(3, 1), pos(5, 1), pos(6, 1))
+
+	h.AssertCanvas(`
+
+  ..::##
+  ..::##`)
+
+	h.Press("ctrl+z")
+	h.AssertCanvas(``)
+}
+
+func TestGradientToolDither(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.RunExLine("set ramp .#")
+	h.RunExLine("select rect 0 0 4 4")
+	h.Press("alt+g")
+	h.Press("tab")
+	h.Drag(pos(0, 0), pos(0, 3))
+
+	// Rows in between mix both characters, more of the second the further down they are
+	h.AssertCanvas(`
+....
+.#..
+#.##
+####`)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Size of the simulated terminal used by the harness.
+const HARNESS_SCREEN_WIDTH = 100
+const HARNESS_SCREEN_HEIGHT = 40
+
+// Drives an app on a simulation screen. Events are handled and drawn one at a time, like the
+// main loop does when events arrive slowly.
+type Harness struct {
+	t      *testing.T
+	Screen tcell.SimulationScreen
+	App    *App
+	Editor *Editor
+}
+
+// Writes log output to the test log, so that it shows up for failing tests.
+type testLogWriter struct {
+	t *testing.T
+}
+
+func (w testLogWriter) Write(b []byte) (int, error) {
+	w.t.Log(strings.TrimSuffix(string(b), "\n"))
+	return len(b), nil
+}
+
+// Starts an editor with the default settings and a blank canvas of the given size. The
+// configuration directory points into a temporary directory, so that nothing the test does
+// touches the user's settings.
+func NewHarness(t *testing.T, width, height int) *Harness {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	s := tcell.NewSimulationScreen("UTF-8")
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	s.SetSize(HARNESS_SCREEN_WIDTH, HARNESS_SCREEN_HEIGHT)
+
+	data := DefaultUserData()
+	data.Config.Width, data.Config.Height = width, height
+
+	h := &Harness{t: t, Screen: s}
+	h.App = NewAppWithScreen(s, log.New(testLogWriter{t}, "", 0), data)
+	h.Editor = h.App.editor
+	// The simulation screen has no mouse, which starts the editor in cursor mode
+	h.Editor.SetCursorMode(false)
+	h.App.Draw(0)
+	return h
+}
+
+// Handles an event and redraws the screen.
+func (h *Harness) Send(ev tcell.Event) {
+	h.t.Helper()
+	h.App.HandleEvent(ev)
+	h.App.Draw(0)
+}
+
+// Presses a key given in keymap syntax, such as "ctrl+z" or "alt+[".
+func (h *Harness) Press(key string) {
+	h.t.Helper()
+	k, err := ParseKeyString(key)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	h.Send(tcell.NewEventKey(k.Key, k.Rune, k.Modifiers))
+}
+
+// Types each character of the string.
+func (h *Harness) Type(s string) {
+	h.t.Helper()
+	for _, r := range s {
+		h.Send(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+}
+
+// Runs a line of the command line, as if typed after Alt+:.
+func (h *Harness) RunExLine(line string) {
+	h.t.Helper()
+	h.Editor.RunExLine(line)
+	h.App.Draw(0)
+}
+
+// Screen position of a canvas position.
+func (h *Harness) ScreenPos(p adraw.Position) (int, int) {
+	m := h.Editor
+	return p.X + m.offsetX + m.sx, p.Y + m.offsetY + m.sy
+}
+
+// Moves the mouse to a canvas position with the given buttons held.
+func (h *Harness) Mouse(p adraw.Position, buttons tcell.ButtonMask) {
+	h.t.Helper()
+	x, y := h.ScreenPos(p)
+	h.Send(tcell.NewEventMouse(x, y, buttons, tcell.ModNone))
+}
+
+// Clicks the left button at a canvas position.
+func (h *Harness) Click(p adraw.Position) {
+	h.t.Helper()
+	h.Drag(p)
+}
+
+// Presses the left button at the first canvas position, moves through the rest and releases
+// the button at the last one.
+func (h *Harness) Drag(points ...adraw.Position) {
+	h.t.Helper()
+	for _, p := range points {
+		h.Mouse(p, tcell.Button1)
+	}
+	h.Mouse(points[len(points)-1], tcell.ButtonNone)
+}
+
+// Like Drag, with the right button.
+func (h *Harness) RightDrag(points ...adraw.Position) {
+	h.t.Helper()
+	for _, p := range points {
+		h.Mouse(p, tcell.Button2)
+	}
+	h.Mouse(points[len(points)-1], tcell.ButtonNone)
+}
+
+func (h *Harness) Canvas() *adraw.Buffer {
+	return h.Editor.CurrentCanvas()
+}
+
+// Characters of the canvas, one line per row. Trailing blanks are removed from each line and
+// trailing blank lines are dropped.
+func (h *Harness) CanvasText() string {
+	b := h.Canvas()
+	var lines []string
+	for y := range b.Data.Height {
+		line := make([]byte, b.Data.Width)
+		for x := range b.Data.Width {
+			line[x] = b.Data.MustGet(x, y).Value
+			if line[x] == 0 {
+				line[x] = ' '
+			}
+		}
+		lines = append(lines, strings.TrimRight(string(line), " "))
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// Checks the characters of the canvas against the expected text, in the form returned by
+// CanvasText. A leading newline in want is ignored, so that it can start on its own line.
+func (h *Harness) AssertCanvas(want string) {
+	h.t.Helper()
+	want = strings.TrimPrefix(want, "\n")
+	if got := h.CanvasText(); got != want {
+		h.t.Errorf("canvas is\n%s\nwant\n%s", got, want)
+	}
+}
+
+// Checks the size of the canvas.
+func (h *Harness) AssertCanvasSize(width, height int) {
+	h.t.Helper()
+	if b := h.Canvas(); b.Data.Width != width || b.Data.Height != height {
+		h.t.Errorf("canvas is %d x %d, want %d x %d", b.Data.Width, b.Data.Height, width, height)
+	}
+}
+
+// Character and style of a screen cell.
+func (h *Harness) ScreenCell(x, y int) (rune, tcell.Style) {
+	r, _, style, _ := h.Screen.GetContent(x, y)
+	return r, style
+}
+
+// Checks that the screen shows the given text starting at a screen position.
+func (h *Harness) AssertScreenText(x, y int, want string) {
+	h.t.Helper()
+	var got []rune
+	for i := range []rune(want) {
+		r, _ := h.ScreenCell(x+i, y)
+		got = append(got, r)
+	}
+	if string(got) != want {
+		h.t.Errorf("screen at (%d, %d) shows %q, want %q", x, y, string(got), want)
+	}
+}
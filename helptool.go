@@ -1,91 +1,166 @@
 package main
 
-import "github.com/gdamore/tcell/v2"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Maximum size of the help dialog.
+const HELP_WIDTH = 70
+const HELP_HEIGHT = 30
+
+// A group of lines on the help page that is matched against the search as a whole.
+type helpEntry []string
+
+type helpSection struct {
+	title   string
+	entries []helpEntry
+}
 
 type HelpTool struct {
-	currentPage int
+	sections []helpSection
+	search   TextWidget
+	scroll   int
+	// Lines of the last drawn page, used to clamp the scroll position.
+	pageLines int
 }
 
 var (
 	_ Tool = &HelpTool{}
 )
 
-var helpPages [][][]string = [][][]string{
-	{
-		{
-			"press any key to set brush char",
-			"esc: return to brush tool",
-			"ctrl+h: show this help page",
-			"ctrl+q: quit",
-			"ctrl+alt+Q: force quit",
-			"ctrl+f: select fg color",
-			"ctrl+g: select bg color",
-			"ctrl+n: clear canvas",
-			"ctrl+click: pan",
-			"alt+=: increase brush radius",
-			"alt+-: decrease brush radius",
-			"alt+hover: lookup color on canvas",
-			"alt+click: grab character",
-			"alt+drag up: grab fg color",
-			"alt+drag down: grab bg color",
-		},
-		{
-			"alt+1: toggle alpha lock",
-			"alt+2: toggle char lock",
-			"alt+3: toggle fg lock",
-			"alt+4: toggle bg lock",
-			"ctrl+s: save to file",
-			"ctrl+l: load to file",
-			"ctrl+o: import text",
-			"ctrl+p: export text",
-			"ctrl+z: undo",
-			"ctrl+alt+z: redo",
-			"ctrl+c: copy",
-			"ctrl+x: cut",
-			"ctrl+v: paste",
-			"ctrl+a: reset selection",
-			"alt+,: clear selection",
-			"alt+.: fill selection",
-		},
-	},
-	{
-		{
-			"brush (default tool)",
-			"click and drag",
-			"tab to toggle straight line mode",
-			"",
-			"lasso (ctrl+r)",
-			"click and drag to make freeform selection",
-			"",
-			"translate (ctrl+t)",
-			"click and drag to move selected characters",
-			"",
-			"resize (alt+[)",
-			"click and drag to set new canvas dimensions",
-			"enter to commit",
-			"",
+// Help entries that are not bound through the keymap.
+var helpGeneral = []string{
+	"press any key to set brush char",
+	"esc: return to brush tool",
+	"ctrl+click: pan",
+	"alt+hover: lookup color on canvas",
+	"alt+click: grab character",
+	"alt+drag up: grab fg color",
+	"alt+drag down: grab bg color",
+	"(cursor mode) arrows, hjkl: move cursor, with shift or HJKL: move faster",
+	"(cursor mode) space: press or lift the pen, acting as the mouse button",
+}
+
+// Builds the help page from the bindings in the given keymap and the registered tools.
+func NewHelpTool(keymap map[KeyEvent]action.Action) *HelpTool {
+	general := helpSection{title: "General"}
+	for _, ln := range helpGeneral {
+		general.entries = append(general.entries, helpEntry{ln})
+	}
+
+	bindings := helpSection{title: "Actions"}
+	for _, act := range action.All() {
+		keys := keyList(keymap, act)
+		if keys == "" {
+			keys = "unbound"
+		}
+		bindings.entries = append(bindings.entries, helpEntry{
+			fmt.Sprintf("%-32s %s", act.Description(), keys),
+		})
+	}
+
+	tools := helpSection{title: "Tools"}
+	for _, info := range Tools() {
+		header := info.Name
+		if keys := keyList(keymap, info.Action); keys != "" {
+			header = fmt.Sprintf("%s (%s)", info.Name, keys)
+		}
+		e := helpEntry{fmt.Sprintf("%s: %s", header, info.Description)}
+		for _, g := range info.Gestures {
+			e = append(e, "  "+g)
+		}
+		tools.entries = append(tools.entries, e)
+	}
+
+	h := &HelpTool{
+		sections: []helpSection{general, bindings, tools},
+		search: TextWidget{
+			Active: true,
+			Hint:   "type to search...",
 		},
-		{},
-	},
+	}
+	h.search.OnSubmit = func(s string) {}
+	return h
+}
+
+// Comma-separated list of the keys bound to an action.
+func keyList(keymap map[KeyEvent]action.Action, act action.Action) string {
+	var names []string
+	for _, k := range KeysForAction(keymap, act) {
+		names = append(names, k.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// Lines of every section with an entry matching the search, case-insensitively.
+func (e *HelpTool) lines() []string {
+	query := strings.ToLower(strings.TrimSpace(e.search.Contents))
+	var res []string
+	for _, sec := range e.sections {
+		var matched []string
+		for _, entry := range sec.entries {
+			if query == "" || strings.Contains(strings.ToLower(strings.Join(entry, "\n")), query) {
+				matched = append(matched, entry...)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		if len(res) > 0 {
+			res = append(res, "")
+		}
+		res = append(res, sec.title)
+		res = append(res, matched...)
+	}
+	return res
 }
 
 func (e *HelpTool) HandleEvent(m *Editor, event tcell.Event) {
 	switch ev := event.(type) {
 	case *tcell.EventKey:
-		if ev.Key() == tcell.KeyTAB {
-			e.currentPage = (e.currentPage + 1) % len(helpPages)
+		switch ev.Key() {
+		case tcell.KeyUp:
+			e.scroll--
+		case tcell.KeyDown:
+			e.scroll++
+		case tcell.KeyPgUp:
+			e.scroll -= e.pageLines
+		case tcell.KeyPgDn:
+			e.scroll += e.pageLines
+		case tcell.KeyHome:
+			e.scroll = 0
+		case tcell.KeyEnd:
+			e.scroll = len(e.lines())
+		default:
+			old := e.search.Contents
+			e.search.HandleEvent(ev)
+			if e.search.Contents != old {
+				e.scroll = 0
+			}
+		}
+	case *tcell.EventMouse:
+		switch {
+		case ev.Buttons()&tcell.WheelUp != 0:
+			e.scroll--
+		case ev.Buttons()&tcell.WheelDown != 0:
+			e.scroll++
 		}
 	}
 }
 
 func (e *HelpTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
-	r := Area{
-		Width:  70,
-		Height: 20,
+	r := adraw.Area{
+		Width:  min(HELP_WIDTH, w-2),
+		Height: min(HELP_HEIGHT, h-2),
 	}
 	r.X = x + (w-r.Width)/2
 	r.Y = y + (h-r.Height)/2
-	bb := Area{
+	bb := adraw.Area{
 		X:      r.X - 1,
 		Y:      r.Y - 1,
 		Width:  r.Width + 2,
@@ -93,15 +168,28 @@ func (e *HelpTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
 	}
 	BorderBox(p, bb, tcell.StyleDefault)
 	FillRegion(p, r.X, r.Y, r.Width, r.Height, ' ', tcell.StyleDefault)
+	crop := &CropPainter{p: p, area: r}
 
-	SetCenteredString(p, r.X+r.Width/2, r.Y, ProgramName(), tcell.StyleDefault)
-	SetCenteredString(p, r.X+r.Width/2, r.Y+r.Height, "press tab for next page", tcell.StyleDefault)
+	SetCenteredString(crop, r.X+r.Width/2, r.Y, ProgramName(), tcell.StyleDefault)
+	SetString(crop, r.X, r.Y+1, "search: ", tcell.StyleDefault)
+	e.search.Draw(crop, r.X+8, r.Y+1, r.Width-8, 1, lag)
 
-	for y, ln := range helpPages[e.currentPage][0] {
-		SetString(p, r.X, r.Y+2+y, ln, tcell.StyleDefault)
-	}
+	lines := e.lines()
+	// Title and search bar above the list, key hints below it
+	e.pageLines = max(1, r.Height-4)
+	e.scroll = max(0, min(e.scroll, len(lines)-e.pageLines))
 
-	for y, ln := range helpPages[e.currentPage][1] {
-		SetString(p, r.X+r.Width/2, r.Y+2+y, ln, tcell.StyleDefault)
+	for i := range e.pageLines {
+		idx := e.scroll + i
+		if idx >= len(lines) {
+			break
+		}
+		SetString(crop, r.X, r.Y+3+i, lines[idx], tcell.StyleDefault)
 	}
+
+	footer := fmt.Sprintf(
+		"up/down, pgup/pgdn: scroll  esc: close  %d-%d of %d",
+		min(len(lines), e.scroll+1), min(len(lines), e.scroll+e.pageLines), len(lines),
+	)
+	SetString(crop, r.X, r.Y+r.Height-1, footer, tcell.StyleDefault.Foreground(tcell.ColorGray))
 }
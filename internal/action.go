@@ -32,4 +32,124 @@ const (
 	BgLock
 	ClearSelection
 	FillSelection
+	Settings
+	Brush
+	CommandPalette
+	CommandLine
+	CursorMode
+	RecordMacro
+	PlayMacro
+	SystemCopy
+	SystemCopyANSI
+	SelectRegister
+	RegisterPicker
+	StampOptions
+	TileFill
+	BrushShape
+	CustomBrush
+	PatternBrush
+	Gradient
+	Spray
+	Eraser
+	EraseMode
+	Symmetry
+	SymmetryCenter
+	FindReplace
+	Palette
 )
+
+type actionInfo struct {
+	name        string
+	description string
+}
+
+var actionInfos = [...]actionInfo{
+	Save:                {"save", "save to file"},
+	Load:                {"load", "load from file"},
+	Import:              {"import", "import text"},
+	Export:              {"export", "export text"},
+	Reset:               {"reset", "reset editor"},
+	CenterCanvas:        {"center-canvas", "center canvas"},
+	Quit:                {"quit", "quit"},
+	ForceQuit:           {"force-quit", "quit without saving"},
+	Help:                {"help", "show help page"},
+	NewCanvas:           {"new-canvas", "clear canvas"},
+	FgColorSelector:     {"fg-color", "select fg color"},
+	BgColorSelector:     {"bg-color", "select bg color"},
+	Lasso:               {"lasso", "lasso tool"},
+	Translate:           {"translate", "translate tool"},
+	Deselect:            {"deselect", "reset selection"},
+	Copy:                {"copy", "copy"},
+	Cut:                 {"cut", "cut"},
+	Paste:               {"paste", "paste"},
+	Undo:                {"undo", "undo"},
+	Redo:                {"redo", "redo"},
+	IncreaseBrushRadius: {"increase-brush-radius", "increase brush radius"},
+	DecreaseBrushRadius: {"decrease-brush-radius", "decrease brush radius"},
+	Resize:              {"resize", "resize tool"},
+	AlphaLock:           {"alpha-lock", "toggle alpha lock"},
+	CharLock:            {"char-lock", "toggle char lock"},
+	FgLock:              {"fg-lock", "toggle fg lock"},
+	BgLock:              {"bg-lock", "toggle bg lock"},
+	ClearSelection:      {"clear-selection", "clear selection"},
+	FillSelection:       {"fill-selection", "fill selection"},
+	Settings:            {"settings", "edit settings"},
+	Brush:               {"brush", "brush tool"},
+	CommandPalette:      {"command-palette", "open command palette"},
+	CommandLine:         {"command-line", "open : command line"},
+	CursorMode:          {"cursor-mode", "toggle keyboard cursor mode"},
+	RecordMacro:         {"record-macro", "start or stop recording a macro"},
+	PlayMacro:           {"play-macro", "play a macro at the cursor"},
+	SystemCopy:          {"system-copy", "copy to system clipboard"},
+	SystemCopyANSI:      {"system-copy-ansi", "copy to system clipboard with colors"},
+	SelectRegister:      {"select-register", "use a register for the next copy, cut or paste"},
+	RegisterPicker:      {"registers", "pick from clipboard history and registers"},
+	StampOptions:        {"stamp-options", "set stamp anchor and transparency"},
+	TileFill:            {"tile-fill", "fill selection with tiled clipboard"},
+	BrushShape:          {"brush-shape", "cycle the brush shape"},
+	CustomBrush:         {"custom-brush", "use the selection as the brush shape"},
+	PatternBrush:        {"pattern-brush", "toggle cycling the brush through the ramp"},
+	Gradient:            {"gradient", "gradient tool"},
+	Spray:               {"spray", "spray tool"},
+	Eraser:              {"eraser", "eraser tool"},
+	EraseMode:           {"erase-mode", "cycle erasing everything, characters or colors"},
+	Symmetry:            {"symmetry", "cycle the mirror and radial symmetry modes"},
+	SymmetryCenter:      {"symmetry-center", "move the center of symmetry"},
+	FindReplace:         {"find-replace", "replace characters and colors"},
+	Palette:             {"palette", "show the palette and recent colors"},
+}
+
+// Returns every action, in declaration order.
+func All() []Action {
+	res := make([]Action, len(actionInfos))
+	for i := range actionInfos {
+		res[i] = Action(i)
+	}
+	return res
+}
+
+// Name of the action as used in configuration files, such as "fill-selection".
+func (a Action) String() string {
+	if a < 0 || int(a) >= len(actionInfos) {
+		return "unknown"
+	}
+	return actionInfos[a].name
+}
+
+// Short human-readable description of the action.
+func (a Action) Description() string {
+	if a < 0 || int(a) >= len(actionInfos) {
+		return "unknown action"
+	}
+	return actionInfos[a].description
+}
+
+// Looks up an action by its name.
+func Parse(name string) (Action, bool) {
+	for i, info := range actionInfos {
+		if info.name == name {
+			return Action(i), true
+		}
+	}
+	return 0, false
+}
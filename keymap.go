@@ -1,6 +1,19 @@
 package main
 
-import "github.com/gdamore/tcell/v2"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
 
 // Key events formatted in a more consistent, non-redundant matter.
 type KeyEvent struct {
@@ -34,3 +47,234 @@ func RuneEvent(r rune, mod tcell.ModMask) KeyEvent {
 		Rune:      r,
 	}
 }
+
+// Names of non-rune keys, as used in keymap files.
+var keyNames = map[tcell.Key]string{
+	tcell.KeyEnter:      "enter",
+	tcell.KeyTab:        "tab",
+	tcell.KeyBacktab:    "backtab",
+	tcell.KeyEscape:     "esc",
+	tcell.KeyBackspace2: "backspace",
+	tcell.KeyDelete:     "delete",
+	tcell.KeyInsert:     "insert",
+	tcell.KeyUp:         "up",
+	tcell.KeyDown:       "down",
+	tcell.KeyLeft:       "left",
+	tcell.KeyRight:      "right",
+	tcell.KeyHome:       "home",
+	tcell.KeyEnd:        "end",
+	tcell.KeyPgUp:       "pgup",
+	tcell.KeyPgDn:       "pgdn",
+	tcell.KeyF1:         "f1",
+	tcell.KeyF2:         "f2",
+	tcell.KeyF3:         "f3",
+	tcell.KeyF4:         "f4",
+	tcell.KeyF5:         "f5",
+	tcell.KeyF6:         "f6",
+	tcell.KeyF7:         "f7",
+	tcell.KeyF8:         "f8",
+	tcell.KeyF9:         "f9",
+	tcell.KeyF10:        "f10",
+	tcell.KeyF11:        "f11",
+	tcell.KeyF12:        "f12",
+}
+
+// Characters that can be combined with ctrl to form a control key, other than letters.
+var ctrlPunctuation = map[rune]tcell.Key{
+	' ':  tcell.KeyCtrlSpace,
+	'[':  tcell.KeyCtrlLeftSq,
+	'\\': tcell.KeyCtrlBackslash,
+	']':  tcell.KeyCtrlRightSq,
+	'^':  tcell.KeyCtrlCarat,
+	'_':  tcell.KeyCtrlUnderscore,
+}
+
+// Parses a key description such as "ctrl+alt+q", "alt+=" or "f1" into the key event it
+// matches after normalization with ParseEvent.
+func ParseKeyString(s string) (KeyEvent, error) {
+	var mods tcell.ModMask
+	// Modifiers are split off the original string rather than a lowercased copy, which can
+	// differ in length
+	rest := s
+	for {
+		idx := strings.Index(rest, "+")
+		// A trailing "+" is the plus key itself
+		if idx <= 0 || idx == len(rest)-1 {
+			break
+		}
+		switch mod := strings.ToLower(rest[:idx]); mod {
+		case "ctrl":
+			mods |= tcell.ModCtrl
+		case "alt":
+			mods |= tcell.ModAlt
+		case "shift":
+			mods |= tcell.ModShift
+		case "meta":
+			mods |= tcell.ModMeta
+		default:
+			return KeyEvent{}, fmt.Errorf("unknown modifier %q in key %q", mod, s)
+		}
+		rest = rest[idx+1:]
+	}
+
+	name := strings.ToLower(rest)
+	for k, n := range keyNames {
+		if n == name {
+			return KeyEvent{Key: k, Modifiers: mods}, nil
+		}
+	}
+
+	// Keep the case of the key itself, so that "alt+Q" is distinct from "alt+q"
+	key := []rune(rest)
+	if name == "space" {
+		key = []rune{' '}
+	}
+	if len(key) != 1 {
+		return KeyEvent{}, fmt.Errorf("unknown key %q", s)
+	}
+	r := key[0]
+	if r < ' ' || r > unicode.MaxASCII {
+		return KeyEvent{}, fmt.Errorf("unknown key %q", s)
+	}
+
+	if mods&tcell.ModCtrl != 0 {
+		mods &^= tcell.ModCtrl
+		lower := unicode.ToLower(r)
+		if lower >= 'a' && lower <= 'z' {
+			return KeyEvent{Key: tcell.KeyCtrlA + tcell.Key(lower-'a'), Modifiers: mods}, nil
+		}
+		if k, ok := ctrlPunctuation[r]; ok {
+			return KeyEvent{Key: k, Modifiers: mods}, nil
+		}
+		return KeyEvent{}, fmt.Errorf("key %q cannot be combined with ctrl", string(r))
+	}
+
+	return RuneEvent(r, mods), nil
+}
+
+// Formats the key event in the syntax accepted by ParseKeyString.
+func (k KeyEvent) String() string {
+	var sb strings.Builder
+	mods := k.Modifiers
+	var key string
+	if k.Key == tcell.KeyRune {
+		key = string(k.Rune)
+		if k.Rune == ' ' {
+			key = "space"
+		}
+	} else if name, ok := keyNames[k.Key]; ok {
+		key = name
+	} else if k.Key >= tcell.KeyCtrlA && k.Key <= tcell.KeyCtrlZ {
+		mods |= tcell.ModCtrl
+		key = string(rune('a' + k.Key - tcell.KeyCtrlA))
+	} else {
+		for r, ck := range ctrlPunctuation {
+			if ck == k.Key {
+				mods |= tcell.ModCtrl
+				key = string(r)
+				if r == ' ' {
+					key = "space"
+				}
+			}
+		}
+	}
+
+	if mods&tcell.ModCtrl != 0 {
+		sb.WriteString("ctrl+")
+	}
+	if mods&tcell.ModAlt != 0 {
+		sb.WriteString("alt+")
+	}
+	if mods&tcell.ModShift != 0 {
+		sb.WriteString("shift+")
+	}
+	if mods&tcell.ModMeta != 0 {
+		sb.WriteString("meta+")
+	}
+	sb.WriteString(key)
+	return sb.String()
+}
+
+// Returns the keys bound to the given action, sorted by their string representation.
+func KeysForAction(keymap map[KeyEvent]action.Action, act action.Action) []KeyEvent {
+	var keys []KeyEvent
+	for k, a := range keymap {
+		if a == act {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b KeyEvent) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return keys
+}
+
+// Applies the bindings of a keymap file on top of the given keymap. The file is a JSON
+// object mapping key strings to action names; an empty action name removes the binding.
+// Invalid and duplicate entries are skipped, and reported together in the returned error.
+func LoadKeymap(keymap map[KeyEvent]action.Action, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('{') {
+		return errors.New("keymap must be a JSON object")
+	}
+
+	var errs []error
+	seen := map[KeyEvent]string{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		keyString := tok.(string)
+		var actionName string
+		if err := dec.Decode(&actionName); err != nil {
+			return fmt.Errorf("binding for %q: %w", keyString, err)
+		}
+
+		key, err := ParseKeyString(keyString)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if prev, ok := seen[key]; ok {
+			errs = append(errs, fmt.Errorf("key %q is bound more than once (also %q)", keyString, prev))
+			continue
+		}
+		seen[key] = keyString
+
+		if actionName == "" {
+			delete(keymap, key)
+			continue
+		}
+		act, ok := action.Parse(actionName)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown action %q for key %q", actionName, keyString))
+			continue
+		}
+		keymap[key] = act
+	}
+
+	return errors.Join(errs...)
+}
+
+// Applies the user's keymap file, if there is one, on top of the given keymap.
+func LoadKeymapFile(keymap map[KeyEvent]action.Action) error {
+	path, err := ConfigPath("keymap.json")
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := LoadKeymap(keymap, f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseKeyString(t *testing.T) {
+	tests := []struct {
+		s    string
+		want KeyEvent
+	}{
+		{"q", RuneEvent('q', tcell.ModNone)},
+		{"alt+q", RuneEvent('q', tcell.ModAlt)},
+		{"alt+Q", RuneEvent('Q', tcell.ModAlt)},
+		{"Alt+Q", RuneEvent('Q', tcell.ModAlt)},
+		{"ALT+=", RuneEvent('=', tcell.ModAlt)},
+		{"alt++", RuneEvent('+', tcell.ModAlt)},
+		{"space", RuneEvent(' ', tcell.ModNone)},
+		{"ctrl+z", KeyEvent{Key: tcell.KeyCtrlZ}},
+		{"ctrl+alt+Z", KeyEvent{Key: tcell.KeyCtrlZ, Modifiers: tcell.ModAlt}},
+		{"ctrl+]", KeyEvent{Key: tcell.KeyCtrlRightSq}},
+		{"ctrl+space", KeyEvent{Key: tcell.KeyCtrlSpace}},
+		{"shift+up", KeyEvent{Key: tcell.KeyUp, Modifiers: tcell.ModShift}},
+		{"f12", KeyEvent{Key: tcell.KeyF12}},
+	}
+	for _, tt := range tests {
+		got, err := ParseKeyString(tt.s)
+		if err != nil {
+			t.Errorf("ParseKeyString(%q): %v", tt.s, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseKeyString(%q) = %+v, want %+v", tt.s, got, tt.want)
+		}
+	}
+
+	for _, s := range []string{"hyper+q", "ctrl+1", "alt+qq", "f13", "alt+é", "ALT+İ", "İİİİ"} {
+		if _, err := ParseKeyString(s); err == nil {
+			t.Errorf("ParseKeyString(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestKeyStringRoundTrip(t *testing.T) {
+	for k := range defaultKeymap() {
+		s := k.String()
+		got, err := ParseKeyString(s)
+		if err != nil {
+			t.Errorf("%+v formats as %q, which does not parse: %v", k, s, err)
+			continue
+		}
+		if got != k {
+			t.Errorf("%+v formats as %q, which parses as %+v", k, s, got)
+		}
+	}
+
+	// Events from the terminal match the keys they are written as
+	ev := tcell.NewEventKey(tcell.KeyCtrlK, 0, tcell.ModCtrl)
+	if got := ParseEvent(ev).String(); got != "ctrl+k" {
+		t.Errorf("ctrl+k event formats as %q", got)
+	}
+}
+
+func TestDefaultKeymapLeavesTerminalKeys(t *testing.T) {
+	// Many terminals send these for backspace and tab
+	for _, k := range []tcell.Key{tcell.KeyCtrlH, tcell.KeyCtrlI} {
+		if act, ok := defaultKeymap()[KeyEvent{Key: k}]; ok {
+			t.Errorf("%s is bound to %v", KeyEvent{Key: k}, act)
+		}
+	}
+}
+
+func TestLoadKeymap(t *testing.T) {
+	keymap := map[KeyEvent]action.Action{
+		RuneEvent('z', tcell.ModAlt): action.Undo,
+		RuneEvent('x', tcell.ModAlt): action.Redo,
+	}
+	err := LoadKeymap(keymap, strings.NewReader(`{
+		"alt+u": "undo",
+		"alt+x": "",
+		"alt+U": "no-such-action",
+		"hyper+q": "undo",
+		"ALT+u": "redo"
+	}`))
+	if err == nil {
+		t.Fatal("invalid entries were not reported")
+	}
+	for _, want := range []string{
+		`unknown action "no-such-action"`,
+		`unknown modifier "hyper"`,
+		`key "ALT+u" is bound more than once`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	// Valid entries are applied despite the errors
+	if got := keymap[RuneEvent('u', tcell.ModAlt)]; got != action.Undo {
+		t.Errorf("alt+u is bound to %v, want undo", got)
+	}
+	if _, ok := keymap[RuneEvent('x', tcell.ModAlt)]; ok {
+		t.Error("alt+x is still bound")
+	}
+	if got := keymap[RuneEvent('z', tcell.ModAlt)]; got != action.Undo {
+		t.Errorf("alt+z is bound to %v, want undo", got)
+	}
+
+	if err := LoadKeymap(keymap, strings.NewReader(`["undo"]`)); err == nil {
+		t.Error("a keymap that is not an object was accepted")
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Registers that macros can be stored in.
+const MACRO_REGISTERS = "abcdefghijklmnopqrstuvwxyz"
+
+// How deeply macros may play other macros, which stops a macro from playing itself forever.
+const MACRO_MAX_DEPTH = 8
+
+// A recorded sequence of events. Mouse positions are stored relative to the canvas position
+// of the cursor when recording started, so that the macro can be replayed anywhere.
+type Macro []RecordedEvent
+
+type macroRecording struct {
+	register rune
+	start    time.Time
+	anchor   adraw.Position
+	events   Macro
+}
+
+// Canvas position under the cursor.
+func (m *Editor) cursorCanvasPosition() adraw.Position {
+	return adraw.Position{X: m.cursorX - m.offsetX, Y: m.cursorY - m.offsetY}
+}
+
+func (m *Editor) StartMacroRecording(register rune) {
+	m.recording = &macroRecording{
+		register: register,
+		start:    time.Now(),
+		anchor:   m.cursorCanvasPosition(),
+	}
+}
+
+func (m *Editor) StopMacroRecording() {
+	rec := m.recording
+	if rec == nil {
+		return
+	}
+	m.recording = nil
+
+	// Release the mouse if recording stopped in the middle of a drag, so that playback
+	// finishes what it starts
+	for i := len(rec.events) - 1; i >= 0; i-- {
+		if ev := rec.events[i]; ev.Type == RecordedMouse {
+			if ev.Buttons != tcell.ButtonNone {
+				ev.Buttons = tcell.ButtonNone
+				rec.events = append(rec.events, ev)
+			}
+			break
+		}
+	}
+
+	m.macros[rec.register] = rec.events
+	if !m.app.DryRun {
+		if err := SaveMacroFile(m.macros); err != nil {
+			m.app.Logger.Printf("Error saving macros: %v", err)
+			m.notification.PushNotification("Error saving macros", err.Error(), NotificationCritical)
+			return
+		}
+	}
+	m.notification.PushNotification(
+		"",
+		fmt.Sprintf("Recorded %d events into register %c", len(rec.events), rec.register),
+		NotificationNormal,
+	)
+}
+
+// Converts an event for the macro being recorded.
+func (m *Editor) macroEvent(event tcell.Event) (RecordedEvent, bool) {
+	rec := m.recording
+	r, ok := RecordEvent(event, rec.start)
+	if !ok || r.Type == RecordedResize {
+		return r, false
+	}
+	if r.Type == RecordedMouse {
+		r.X -= m.sx + m.offsetX + rec.anchor.X
+		r.Y -= m.sy + m.offsetY + rec.anchor.Y
+	}
+	return r, true
+}
+
+// Replays the macro in a register with the cursor as its origin. Everything the macro
+// changes on the canvas is undone as a single step.
+func (m *Editor) PlayMacro(register rune) error {
+	macro, ok := m.macros[register]
+	if !ok {
+		return fmt.Errorf("register %c is empty", register)
+	}
+	if m.macroDepth >= MACRO_MAX_DEPTH {
+		return errors.New("too many nested macros")
+	}
+
+	m.macroDepth++
+	defer func() {
+		m.macroDepth--
+	}()
+
+	anchor := m.cursorCanvasPosition()
+	startCanvas := m.CurrentCanvas()
+	startPos := m.undoHistoryPos
+	for _, r := range macro {
+		if r.Type == RecordedMouse {
+			// Offsets are read for each event, since the macro may pan the canvas
+			r.X += anchor.X + m.offsetX + m.sx
+			r.Y += anchor.Y + m.offsetY + m.sy
+		}
+		if ev := r.Event(); ev != nil {
+			m.HandleEvent(ev)
+		}
+	}
+
+	if m.macroDepth == 1 {
+		m.squashHistory(startPos, startCanvas)
+	}
+	return nil
+}
+
+// Merges the undo history entries committed since the history was at pos into one, as long
+// as the canvas at pos is still the given one.
+func (m *Editor) squashHistory(pos int, canvas *adraw.Buffer) {
+	if m.undoHistoryPos <= pos+1 || len(m.undoHistory) <= pos || m.undoHistory[pos] != canvas {
+		return
+	}
+	if m.savedUndoIndex == m.undoHistoryPos {
+		m.savedUndoIndex = pos + 1
+	} else if m.savedUndoIndex > pos {
+		m.historyChanged = true
+	}
+	m.undoHistory = m.undoHistory[:pos+1]
+	m.undoHistoryPos = pos + 1
+}
+
+// Reads the macros saved in macros.json in the configuration directory. A missing file is
+// not an error.
+func LoadMacroFile() (map[rune]Macro, error) {
+	macros := map[rune]Macro{}
+	path, err := ConfigPath("macros.json")
+	if err != nil {
+		return macros, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return macros, nil
+	} else if err != nil {
+		return macros, err
+	}
+
+	var stored map[string]Macro
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return macros, fmt.Errorf("%s: %w", path, err)
+	}
+	if macros, err = DecodeMacros(stored); err != nil {
+		return macros, fmt.Errorf("%s: %w", path, err)
+	}
+	return macros, nil
+}
+
+// Converts macros to a map keyed by register names, for storing as JSON.
+func EncodeMacros(macros map[rune]Macro) map[string]Macro {
+	stored := map[string]Macro{}
+	for reg, macro := range macros {
+		stored[string(reg)] = macro
+	}
+	return stored
+}
+
+// Converts macros stored by EncodeMacros back, checking the register names.
+func DecodeMacros(stored map[string]Macro) (map[rune]Macro, error) {
+	macros := map[rune]Macro{}
+	for reg, macro := range stored {
+		if len([]rune(reg)) != 1 || !strings.Contains(MACRO_REGISTERS, reg) {
+			return map[rune]Macro{}, fmt.Errorf("invalid register %q", reg)
+		}
+		macros[[]rune(reg)[0]] = macro
+	}
+	return macros, nil
+}
+
+// Writes all macros to macros.json in the configuration directory.
+func SaveMacroFile(macros map[rune]Macro) error {
+	path, err := ConfigPath("macros.json")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(EncodeMacros(macros))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Modal tool that waits for the name of a register.
+type RegisterPromptTool struct {
+	prompt    string
+	registers string
+	onSelect  func(r rune)
+}
+
+var (
+	_ Tool = &RegisterPromptTool{}
+)
+
+func (e *RegisterPromptTool) HandleEvent(m *Editor, event tcell.Event) {
+	if ev, ok := event.(*tcell.EventKey); ok && ev.Key() == tcell.KeyRune {
+		if strings.ContainsRune(e.registers, ev.Rune()) {
+			m.ClearModalTool()
+			e.onSelect(ev.Rune())
+		}
+	}
+}
+
+func (e *RegisterPromptTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
+	r := adraw.Area{
+		Width:  50,
+		Height: 2,
+	}
+	r.X = x + (w-r.Width)/2
+	r.Y = y + (h-r.Height)/2
+	bb := adraw.Area{
+		X:      r.X - 1,
+		Y:      r.Y - 1,
+		Width:  r.Width + 2,
+		Height: r.Height + 2,
+	}
+	BorderBox(p, bb, tcell.StyleDefault)
+	FillRegion(p, r.X, r.Y, r.Width, r.Height, ' ', tcell.StyleDefault)
+	SetString(p, r.X, r.Y, e.prompt, tcell.StyleDefault)
+	SetString(p, r.X, r.Y+1, "press a register key, esc to cancel", tcell.StyleDefault.Foreground(tcell.ColorGray))
+}
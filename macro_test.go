@@ -0,0 +1,140 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestMacroReplay(t *testing.T) {
+	h := NewHarness(t, 20, 6)
+	h.Type("o")
+	h.Mouse(pos(1, 0), 0)
+	h.Press("alt+q")
+	h.Type("a")
+	h.Drag(pos(1, 0), pos(3, 0))
+	h.Drag(pos(5, 1))
+	h.Press("alt+q")
+
+	// The macro is played with the cursor as its origin
+	h.Mouse(pos(10, 3), 0)
+	h.Press("alt+@")
+	h.Type("a")
+	h.AssertCanvas(`
+ ooo
+     o
+
+          ooo
+              o`)
+
+	// Both strokes of the macro are undone together
+	h.Press("ctrl+z")
+	h.AssertCanvas(`
+ ooo
+     o`)
+}
+
+func TestMacroInCursorMode(t *testing.T) {
+	h := NewHarness(t, 20, 6)
+	h.Type("o")
+	h.Mouse(pos(1, 1), 0)
+	h.Press("alt+k")
+	h.Press("alt+q")
+	h.Type("a")
+	h.Type(" ll ")
+	h.Press("alt+q")
+	h.AssertCanvas(`
+
+ ooo`)
+
+	// Only the keys are recorded, not the mouse events made from them
+	if got := len(h.Editor.macros['a']); got != 4 {
+		t.Errorf("recorded %d events, want 4", got)
+	}
+
+	h.Mouse(pos(1, 3), 0)
+	h.Press("alt+@")
+	h.Type("a")
+	h.AssertCanvas(`
+
+ ooo
+
+ ooo`)
+	if got := h.Editor.cursorCanvasPosition(); got != pos(3, 3) {
+		t.Errorf("cursor is at %v after the macro, want (3, 3)", got)
+	}
+}
+
+func TestSquashHistory(t *testing.T) {
+	h := NewHarness(t, 10, 2)
+	m := h.Editor
+	start, pos0 := m.CurrentCanvas(), m.undoHistoryPos
+	h.Type("x")
+	h.Click(pos(0, 0))
+	h.Click(pos(1, 0))
+	h.Click(pos(2, 0))
+
+	// Squashing does nothing once the history no longer goes through the given canvas
+	m.squashHistory(pos0, h.Canvas())
+	if got := m.undoHistoryPos; got != pos0+3 {
+		t.Fatalf("history is at %d after squashing with the wrong canvas, want %d", got, pos0+3)
+	}
+
+	m.squashHistory(pos0, start)
+	if got := m.undoHistoryPos; got != pos0+1 {
+		t.Errorf("history is at %d after squashing, want %d", got, pos0+1)
+	}
+	h.AssertCanvas(`xxx`)
+	h.Press("ctrl+z")
+	h.AssertCanvas(``)
+}
+
+func TestMacroPlayingItself(t *testing.T) {
+	h := NewHarness(t, 10, 2)
+	m := h.Editor
+	start := time.Now()
+	var macro Macro
+	for _, ev := range []tcell.Event{
+		tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyRune, '@', tcell.ModAlt),
+		tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+	} {
+		r, _ := RecordEvent(ev, start)
+		macro = append(macro, r)
+	}
+	m.macros['a'] = macro
+
+	// Nesting stops at the limit instead of recursing forever
+	if err := m.PlayMacro('a'); err != nil {
+		t.Fatal(err)
+	}
+	if m.macroDepth != 0 {
+		t.Errorf("macro depth is %d after playing, want 0", m.macroDepth)
+	}
+	if m.hasModalTool {
+		t.Errorf("the innermost register prompt is still open")
+	}
+}
+
+func TestMacroFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	r, _ := RecordEvent(tcell.NewEventMouse(3, -2, tcell.Button1, tcell.ModNone), time.Now())
+	macros := map[rune]Macro{'q': {r}}
+	if err := SaveMacroFile(macros); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadMacroFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got['q']) != 1 || got['q'][0] != r {
+		t.Errorf("loaded macros %+v, want %+v", got, macros)
+	}
+
+	for _, reg := range []string{"A", "ab", "1", ""} {
+		if _, err := DecodeMacros(map[string]Macro{reg: {r}}); err == nil {
+			t.Errorf("register %q was accepted", reg)
+		}
+	}
+}
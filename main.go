@@ -1,6 +1,10 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
 	"github.com/gdamore/tcell/v2"
 )
 
@@ -12,7 +16,23 @@ var (
 )
 
 func main() {
-	a := NewApp()
+	flags := flag.NewFlagSet("ascii-draw", flag.ExitOnError)
+	flags.Usage = func() {
+		printUsage(flags.Output())
+	}
+	record := flags.String("record", "", "record every event of the session to this file")
+	flags.Parse(os.Args[1:])
+
+	// Subcommands run headless, without ever initializing the screen
+	if flags.NArg() > 0 {
+		if err := RunSubcommand(flags.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "ascii-draw: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	a := NewApp(*record)
 	defer a.Quit()
 	a.Loop()
 }
@@ -27,6 +27,15 @@ func (m *MultiWidget) Update() {
 	}
 }
 
+func (m *MultiWidget) Animating() bool {
+	for _, w := range m.widgets {
+		if w.Animating() {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *MultiWidget) Draw(p Painter, x, y, w, h int, lag float64) {
 	for _, wd := range m.widgets {
 		wd.Draw(p, x, y, w, h, lag)
@@ -5,10 +5,12 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/Fekinox/ascii-draw/adraw"
 	"github.com/gdamore/tcell/v2"
 )
 
 const NOTIFICATION_WIDGET_MAX_WIDTH int = 40
+const NOTIFICATION_DEFAULT_DURATION = 10 * time.Second
 
 type NotificationPriority int
 
@@ -33,6 +35,9 @@ type NotificationWidget struct {
 	sy        int
 	sw        int
 	sh        int
+
+	// How long notifications stay on screen. Zero for NOTIFICATION_DEFAULT_DURATION.
+	Duration time.Duration
 }
 
 // Greedy word wrapping algorithm
@@ -104,7 +109,7 @@ func (n *NotificationWidget) HandleEvent(event tcell.Event) {
 		cx, cy = cx-n.sx, cy-n.sy
 		if ev.Buttons()&tcell.Button1 != 0 {
 			w, h := NOTIFICATION_WIDGET_MAX_WIDTH, len(n.header)+len(n.body)
-			r := Area{
+			r := adraw.Area{
 				X:      n.sx + (n.sw-w)/2 - 1,
 				Y:      n.sy,
 				Width:  w + 2,
@@ -118,18 +123,27 @@ func (n *NotificationWidget) HandleEvent(event tcell.Event) {
 }
 
 func (n *NotificationWidget) Update() {
-	if n.active && time.Now().Sub(n.startTime).Seconds() > 10 {
+	d := n.Duration
+	if d == 0 {
+		d = NOTIFICATION_DEFAULT_DURATION
+	}
+	if n.active && time.Now().Sub(n.startTime) > d {
 		n.active = false
 	}
 }
 
+// Active notifications have to be ticked so that they expire on time.
+func (n *NotificationWidget) Animating() bool {
+	return n.active
+}
+
 func (n *NotificationWidget) Draw(p Painter, x, y, w, h int, lag float64) {
 	n.sx, n.sy, n.sw, n.sh = x, y, w, h
 	if !n.active {
 		return
 	}
 
-	r := Area{
+	r := adraw.Area{
 		X:      x,
 		Y:      y + 1,
 		Width:  NOTIFICATION_WIDGET_MAX_WIDTH,
@@ -138,7 +152,7 @@ func (n *NotificationWidget) Draw(p Painter, x, y, w, h int, lag float64) {
 
 	r.X += (w - r.Width) / 2
 
-	bb := Area{
+	bb := adraw.Area{
 		X:      r.X - 1,
 		Y:      r.Y - 1,
 		Width:  r.Width + 2,
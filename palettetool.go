@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Number of recently used colors shown in the palette panel.
+const RECENT_COLORS = 8
+
+// Parses a color for commands: the name of an entry of the palette, or a palette color as
+// accepted by ParsePaletteColor.
+func (m *Editor) ParseColor(s string) (tcell.Color, error) {
+	if c, ok := m.palette.Lookup(s); ok {
+		return c, nil
+	}
+	return ParsePaletteColor(s)
+}
+
+// Shows the canvas in the colors of another palette.
+func (m *Editor) SetPalette(p *adraw.Palette) {
+	m.palette = p
+	m.fullRedraw = true
+}
+
+// Loads the palette file named in the configuration, or goes back to the default palette if
+// there is none.
+func (m *Editor) LoadPaletteFromConfig() {
+	if m.paletteFile == "" {
+		m.SetPalette(adraw.DefaultPalette())
+		return
+	}
+	p, err := adraw.ReadPaletteFile(m.config.ResolvePath(m.paletteFile))
+	if err != nil {
+		m.app.Logger.Printf("Error loading palette: %v", err)
+		m.notification.PushNotification("Error loading palette", err.Error(), NotificationCritical)
+		return
+	}
+	m.SetPalette(p)
+}
+
+// Area of the screen that a color is drawn in by the palette panel.
+type paletteSwatch struct {
+	area  adraw.Area
+	color tcell.Color
+}
+
+// Modal panel showing the entries of the palette and the recently used colors. Clicking a
+// color makes it the foreground, and right clicking the background.
+type PaletteTool struct {
+	selected int
+	// Swatches drawn in the last frame.
+	swatches []paletteSwatch
+	pressed  bool
+}
+
+var (
+	_ Tool = &PaletteTool{}
+)
+
+func (e *PaletteTool) HandleEvent(m *Editor, event tcell.Event) {
+	switch ev := event.(type) {
+	case *tcell.EventMouse:
+		buttons := ev.Buttons() & (tcell.Button1 | ERASE_BUTTONS)
+		if buttons == 0 {
+			e.pressed = false
+			return
+		}
+		if e.pressed {
+			return
+		}
+		e.pressed = true
+		x, y := ev.Position()
+		for _, s := range e.swatches {
+			if !s.area.Contains(x, y) {
+				continue
+			}
+			if buttons&tcell.Button1 != 0 {
+				m.SetFg(s.color)
+			} else {
+				m.SetBg(s.color)
+			}
+			break
+		}
+	case *tcell.EventKey:
+		switch ev.Key() {
+		case tcell.KeyUp:
+			e.selected--
+		case tcell.KeyDown:
+			e.selected++
+		case tcell.KeyLeft:
+			e.selected -= adraw.PaletteSize / 2
+		case tcell.KeyRight:
+			e.selected += adraw.PaletteSize / 2
+		case tcell.KeyEnter:
+			m.SetFg(tcell.ColorValid + tcell.Color(e.selected))
+		case tcell.KeyRune:
+			switch ev.Rune() {
+			case 'k':
+				e.selected--
+			case 'j':
+				e.selected++
+			case 'h':
+				e.selected -= adraw.PaletteSize / 2
+			case 'l':
+				e.selected += adraw.PaletteSize / 2
+			case 'f':
+				m.SetFg(tcell.ColorValid + tcell.Color(e.selected))
+			case 'b':
+				m.SetBg(tcell.ColorValid + tcell.Color(e.selected))
+			}
+		}
+		e.selected = (e.selected + adraw.PaletteSize) % adraw.PaletteSize
+	}
+}
+
+func (e *PaletteTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
+	const rows, colWidth = adraw.PaletteSize / 2, 22
+	r := adraw.Area{
+		Width:  2 * colWidth,
+		Height: rows + 6,
+	}
+	r.X = x + (w-r.Width)/2
+	r.Y = y + (h-r.Height)/2
+	bb := adraw.Area{
+		X:      r.X - 1,
+		Y:      r.Y - 1,
+		Width:  r.Width + 2,
+		Height: r.Height + 2,
+	}
+	BorderBox(p, bb, tcell.StyleDefault)
+	FillRegion(p, r.X, r.Y, r.Width, r.Height, ' ', tcell.StyleDefault)
+	SetCenteredString(p, r.X+r.Width/2, r.Y, "Palette: "+m.palette.Name, tcell.StyleDefault)
+
+	e.swatches = e.swatches[:0]
+	swatch := func(xx, yy int, c tcell.Color) {
+		area := adraw.Area{X: xx, Y: yy, Width: 3, Height: 1}
+		if c == tcell.ColorDefault {
+			SetString(p, xx, yy, "def", tcell.StyleDefault.Foreground(tcell.ColorGray))
+		} else {
+			FillRegion(p, xx, yy, 3, 1, ' ', tcell.StyleDefault.Background(m.palette.Color(c)))
+		}
+		e.swatches = append(e.swatches, paletteSwatch{area: area, color: c})
+	}
+
+	for i := range adraw.PaletteSize {
+		xx, yy := r.X+i/rows*colWidth, r.Y+2+i%rows
+		swatch(xx, yy, tcell.ColorValid+tcell.Color(i))
+
+		st := tcell.StyleDefault
+		if i == e.selected {
+			st = st.Reverse(true)
+		}
+		label := adraw.Area{X: xx + 4, Y: yy, Width: colWidth - 5, Height: 1}
+		crop := &CropPainter{p: p, area: label}
+		SetString(crop, label.X, yy, fmt.Sprintf("%2d %s", i, m.palette.Entry(i).Name), st)
+	}
+
+	recentY := r.Y + 3 + rows
+	SetString(p, r.X, recentY, "recent:", tcell.StyleDefault)
+	for i, c := range m.recentColors {
+		swatch(r.X+8+i*4, recentY, c)
+	}
+
+	footer := "click/f: fg  right click/b: bg  esc: close"
+	SetString(p, r.X, r.Y+r.Height-1, footer, tcell.StyleDefault.Foreground(tcell.ColorGray))
+}
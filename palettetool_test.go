@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestSwapPalette(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.RunExLine("set fg red")
+	h.Type("x")
+	h.Click(pos(0, 0))
+
+	// Changing the entry recolors the drawing, which keeps the index
+	h.RunExLine("palette set 9 #00ff00 leaf")
+	shownFg := func() tcell.Color {
+		_, st := h.ScreenCell(h.ScreenPos(pos(0, 0)))
+		fg, _, _ := st.Decompose()
+		return fg
+	}
+	if got := shownFg(); got != tcell.NewRGBColor(0, 255, 0) {
+		t.Errorf("cell is shown in %v, want the new palette color", got)
+	}
+	c, _ := h.Canvas().Get(0, 0)
+	if fg, _, _ := c.Style.Decompose(); fg != tcell.ColorRed {
+		t.Errorf("cell stores %v, want the index of red", fg)
+	}
+
+	// Entries can be picked by name
+	h.RunExLine("set bg leaf")
+	if h.Editor.bgColor != tcell.ColorRed {
+		t.Errorf("background is %v, want the index of leaf", h.Editor.bgColor)
+	}
+
+	h.RunExLine("palette reset")
+	if got := shownFg(); got != tcell.ColorRed {
+		t.Errorf("cell is shown in %v after resetting the palette", got)
+	}
+}
+
+func TestLinePreviewUsesPalette(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.RunExLine("palette set 9 #00ff00 leaf")
+	h.RunExLine("set fg leaf")
+	h.Type("x")
+	h.Press("tab")
+	h.Mouse(pos(0, 0), tcell.Button1)
+	h.Mouse(pos(2, 0), tcell.Button1)
+
+	_, st := h.ScreenCell(h.ScreenPos(pos(1, 0)))
+	if fg, _, _ := st.Decompose(); fg != tcell.NewRGBColor(0, 255, 0) {
+		t.Errorf("line preview is shown in %v, want the palette color", fg)
+	}
+}
+
+func TestPaletteFiles(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	dir := t.TempDir()
+	gpl := filepath.Join(dir, "forest.gpl")
+	if err := os.WriteFile(gpl, []byte("GIMP Palette\nName: Forest\n34 139 34 leaf\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h.RunExLine("palette load " + gpl)
+	if p := h.Editor.palette; p.Name != "Forest" || p.Entry(0).Name != "leaf" {
+		t.Fatalf("loaded palette %+v", p)
+	}
+
+	saved := filepath.Join(dir, "forest.json")
+	h.RunExLine("palette save " + saved)
+	h.RunExLine("palette reset")
+	h.RunExLine("palette load " + saved)
+	if p := h.Editor.palette; p.Name != "Forest" || p.Entry(0).Color != tcell.NewRGBColor(34, 139, 34) {
+		t.Errorf("palette saved as JSON loaded as %+v", p)
+	}
+	// The palette file of the configuration is loaded when it changes
+	h.RunExLine("palette reset")
+	c := *h.Editor.config
+	c.PaletteFile = gpl
+	h.Editor.ApplyConfig(&c)
+	if p := h.Editor.palette; p.Name != "Forest" {
+		t.Errorf("configured palette loaded as %+v", p)
+	}
+}
+
+func TestPalettePanel(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Press("alt+P")
+	tool := h.Editor.currentModalTool.(*PaletteTool)
+
+	click := func(i int, buttons tcell.ButtonMask) {
+		a := tool.swatches[i].area
+		h.Send(tcell.NewEventMouse(a.X, a.Y, buttons, tcell.ModNone))
+		h.Send(tcell.NewEventMouse(a.X, a.Y, tcell.ButtonNone, tcell.ModNone))
+	}
+	click(2, tcell.Button1)
+	click(4, tcell.Button3)
+	if h.Editor.fgColor != tcell.ColorGreen || h.Editor.bgColor != tcell.ColorNavy {
+		t.Errorf("picked %v on %v, want green on navy", h.Editor.fgColor, h.Editor.bgColor)
+	}
+
+	// The recent colors follow the entries, most recent first
+	want := []tcell.Color{tcell.ColorNavy, tcell.ColorGreen}
+	if got := h.Editor.recentColors; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("recent colors are %v, want %v", got, want)
+	}
+	click(16+1, tcell.Button1)
+	if h.Editor.fgColor != tcell.ColorGreen {
+		t.Errorf("picked %v from the recent colors, want green", h.Editor.fgColor)
+	}
+
+	h.Press("down")
+	h.Type("b")
+	if h.Editor.bgColor != tcell.ColorMaroon {
+		t.Errorf("picked %v with the keyboard, want maroon", h.Editor.bgColor)
+	}
+}
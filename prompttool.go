@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/Fekinox/ascii-draw/adraw"
 	"github.com/gdamore/tcell/v2"
 )
 
@@ -41,7 +42,7 @@ func (e *PromptTool) Draw(
 	x, y, w, h int,
 	lag float64,
 ) {
-	r := Area{
+	r := adraw.Area{
 		Width:  50,
 		Height: 2,
 	}
@@ -51,7 +52,7 @@ func (e *PromptTool) Draw(
 		p:    p,
 		area: r,
 	}
-	bb := Area{
+	bb := adraw.Area{
 		X:      r.X - 1,
 		Y:      r.Y - 1,
 		Width:  r.Width + 2,
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 
+	"github.com/Fekinox/ascii-draw/adraw"
 	"github.com/gdamore/tcell/v2"
 )
 
@@ -43,13 +44,13 @@ func (e *YesNoPromptTool) Draw(
 	x, y, w, h int,
 	lag float64,
 ) {
-	r := Area{
+	r := adraw.Area{
 		Width:  50,
 		Height: 4,
 	}
 	r.X = x + (w-r.Width)/2
 	r.Y = y + (h-r.Height)/2
-	bb := Area{
+	bb := adraw.Area{
 		X:      r.X - 1,
 		Y:      r.Y - 1,
 		Width:  r.Width + 2,
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Version of the recording format, bumped whenever old recordings can no longer be replayed.
+const RECORDING_VERSION = 1
+
+// First line of a recording. It holds everything the session depended on at startup, so that
+// a replay does not read the configuration directory of whoever replays it.
+type RecordingHeader struct {
+	Version int               `json:"version"`
+	Width   int               `json:"width"`
+	Height  int               `json:"height"`
+	Config  *Config           `json:"config"`
+	Keymap  map[string]string `json:"keymap"`
+	Macros  map[string]Macro  `json:"macros"`
+	// Missing from recordings made before registers existed.
+	Registers *ClipboardRegisters `json:"registers,omitempty"`
+	Seed      uint64              `json:"seed,omitempty"`
+}
+
+func NewRecordingHeader(width, height int, data *UserData) RecordingHeader {
+	keymap := map[string]string{}
+	for k, act := range data.Keymap {
+		keymap[k.String()] = act.String()
+	}
+	return RecordingHeader{
+		Version:   RECORDING_VERSION,
+		Width:     width,
+		Height:    height,
+		Config:    data.Config,
+		Keymap:    keymap,
+		Macros:    EncodeMacros(data.Macros),
+		Registers: data.Registers,
+		Seed:      data.Seed,
+	}
+}
+
+// Settings to replay the recording with.
+func (h *RecordingHeader) UserData() (*UserData, error) {
+	if err := h.Config.Validate(); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	keymapJSON, err := json.Marshal(h.Keymap)
+	if err != nil {
+		return nil, err
+	}
+	keymap := map[KeyEvent]action.Action{}
+	if err := LoadKeymap(keymap, bytes.NewReader(keymapJSON)); err != nil {
+		return nil, fmt.Errorf("keymap: %w", err)
+	}
+
+	macros, err := DecodeMacros(h.Macros)
+	if err != nil {
+		return nil, fmt.Errorf("macros: %w", err)
+	}
+
+	registers := h.Registers
+	if registers == nil {
+		registers = NewClipboardRegisters()
+	}
+
+	return &UserData{
+		Config:    h.Config,
+		Keymap:    keymap,
+		Macros:    macros,
+		Registers: registers,
+		Seed:      h.Seed,
+	}, nil
+}
+
+// Writes events to a recording as JSON lines: the header, followed by one RecordedEvent per
+// line. Every event is flushed as it is written, so the recording survives a crash.
+type EventRecorder struct {
+	file  *os.File
+	w     *bufio.Writer
+	enc   *json.Encoder
+	start time.Time
+}
+
+func NewEventRecorder(path string, header RecordingHeader) (*EventRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &EventRecorder{
+		file:  f,
+		w:     bufio.NewWriter(f),
+		start: time.Now(),
+	}
+	r.enc = json.NewEncoder(r.w)
+	if err := r.enc.Encode(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := r.w.Flush(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Appends an event to the recording. Events that cannot be recorded are skipped.
+func (r *EventRecorder) Record(event tcell.Event) error {
+	ev, ok := RecordEvent(event, r.start)
+	if !ok {
+		return nil
+	}
+	if err := r.enc.Encode(ev); err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+func (r *EventRecorder) Close() error {
+	return errors.Join(r.w.Flush(), r.file.Close())
+}
+
+// Reads a recording written by an EventRecorder.
+func ReadRecording(rd io.Reader) (*RecordingHeader, []RecordedEvent, error) {
+	dec := json.NewDecoder(rd)
+	dec.DisallowUnknownFields()
+
+	// Settings added since the recording was made keep their defaults
+	header := RecordingHeader{Config: DefaultConfig()}
+	if err := dec.Decode(&header); err != nil {
+		return nil, nil, fmt.Errorf("header: %w", err)
+	}
+	if header.Version != RECORDING_VERSION {
+		return nil, nil, fmt.Errorf("unsupported recording version %d", header.Version)
+	}
+	if header.Config == nil || header.Width <= 0 || header.Height <= 0 {
+		return nil, nil, errors.New("header: missing screen size or config")
+	}
+
+	var events []RecordedEvent
+	for {
+		var ev RecordedEvent
+		if err := dec.Decode(&ev); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("event %d: %w", len(events)+1, err)
+		}
+		events = append(events, ev)
+	}
+	return &header, events, nil
+}
+
+func ReadRecordingFile(path string) (*RecordingHeader, []RecordedEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return ReadRecording(f)
+}
+
+// Outcome of replaying a recording.
+type ReplayResult struct {
+	// Number of events fed to the app. Less than the length of the recording if the session
+	// quit early.
+	Events int
+	Canvas *adraw.Buffer
+	// SHA-256 of the final canvas in the binary format.
+	Checksum [sha256.Size]byte
+	Screen   tcell.SimulationScreen
+}
+
+// Replays a recording against a simulation screen. Nothing is written to disk, and the
+// logger receives the app's log output.
+func Replay(header *RecordingHeader, events []RecordedEvent, logger *log.Logger) (*ReplayResult, error) {
+	data, err := header.UserData()
+	if err != nil {
+		return nil, err
+	}
+
+	s := tcell.NewSimulationScreen("UTF-8")
+	if err := s.Init(); err != nil {
+		return nil, err
+	}
+	s.SetSize(header.Width, header.Height)
+
+	app := NewAppWithScreen(s, logger, data)
+	app.DryRun = true
+	app.Draw(0)
+
+	res := &ReplayResult{Screen: s}
+	for _, r := range events {
+		if app.WillQuit {
+			break
+		}
+		ev := r.Event()
+		if ev == nil {
+			continue
+		}
+		if r.Type == RecordedResize {
+			s.SetSize(r.Width, r.Height)
+		}
+		app.HandleEvent(ev)
+		app.Draw(0)
+		res.Events++
+	}
+
+	res.Canvas = app.editor.CurrentCanvas()
+	var buf bytes.Buffer
+	if err := res.Canvas.Save(&buf); err != nil {
+		return nil, err
+	}
+	res.Checksum = sha256.Sum256(buf.Bytes())
+	return res, nil
+}
+
+// Contents of a simulation screen as text, one line per row.
+func ScreenText(s tcell.SimulationScreen) string {
+	cells, w, h := s.GetContents()
+	var sb strings.Builder
+	for y := range h {
+		var line []rune
+		for x := range w {
+			runes := cells[y*w+x].Runes
+			if len(runes) == 0 {
+				line = append(line, ' ')
+			} else {
+				line = append(line, runes...)
+			}
+		}
+		sb.WriteString(strings.TrimRight(string(line), " "))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
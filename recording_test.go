@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Header of a recording made before brush shapes had settings.
+const oldRecordingHeader = `{"version":1,"width":60,"height":20,` +
+	`"config":{"width":10,"height":4,"brushCharacter":"#","brushRadius":1,"maxBrushRadius":99,` +
+	`"notificationDuration":10,"saveDirectory":"","logFile":"logfile"},` +
+	`"keymap":{"ctrl+z":"undo"},"macros":{}}`
+
+func TestReplayOldRecording(t *testing.T) {
+	recording := oldRecordingHeader + "\n" + `{"t":5,"type":"key","key":256,"rune":111}` + "\n"
+	header, events, err := ReadRecording(strings.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Config.BrushShape; got != DefaultConfig().BrushShape {
+		t.Errorf("brush shape is %q, want the default", got)
+	}
+
+	res, err := Replay(header, events, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Events != 1 {
+		t.Errorf("replayed %d events, want 1", res.Events)
+	}
+	if w, h := res.Canvas.Data.Width, res.Canvas.Data.Height; w != 10 || h != 4 {
+		t.Errorf("canvas is %d x %d, want 10 x 4", w, h)
+	}
+}
+
+// Records typing a command line that fills part of the canvas.
+func recordFill(t *testing.T, path, command string) {
+	t.Helper()
+	data := DefaultUserData()
+	data.Config.Width, data.Config.Height = 8, 3
+	r, err := NewEventRecorder(path, NewRecordingHeader(30, 10, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := []tcell.Event{tcell.NewEventKey(tcell.KeyRune, ':', tcell.ModAlt)}
+	for _, c := range command {
+		events = append(events, tcell.NewEventKey(tcell.KeyRune, c, tcell.ModNone))
+	}
+	events = append(events, tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	for _, ev := range events {
+		if err := r.Record(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func replayFile(t *testing.T, path string) *ReplayResult {
+	t.Helper()
+	header, events, err := ReadRecordingFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Replay(header, events, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestRecordReplayChecksum(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fill.rec")
+	recordFill(t, path, "fill 1 0 3 2 #")
+
+	first, second := replayFile(t, path), replayFile(t, path)
+	if first.Checksum != second.Checksum {
+		t.Errorf("replaying twice gave checksums %x and %x", first.Checksum, second.Checksum)
+	}
+	if first.Events != len("fill 1 0 3 2 #")+2 {
+		t.Errorf("replayed %d events", first.Events)
+	}
+	var sb strings.Builder
+	if err := first.Canvas.Export(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sb.String(), " ###    \n ###    \n        "; got != want {
+		t.Errorf("replayed canvas is %q, want %q", got, want)
+	}
+
+	other := filepath.Join(dir, "other.rec")
+	recordFill(t, other, "fill 1 0 3 2 %")
+	if replayFile(t, other).Checksum == first.Checksum {
+		t.Error("different recordings have the same checksum")
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Number of copies and cuts kept in the clipboard history.
+const CLIPBOARD_HISTORY_SIZE = 10
+
+// Named clipboard registers, like vim's, and a history of the most recent copies and cuts.
+type ClipboardRegisters struct {
+	Named map[rune]adraw.Grid[adraw.Cell]
+	// Newest first.
+	History []adraw.Grid[adraw.Cell]
+}
+
+func NewClipboardRegisters() *ClipboardRegisters {
+	return &ClipboardRegisters{Named: map[rune]adraw.Grid[adraw.Cell]{}}
+}
+
+// Records a copy or cut in the history, and also in a named register unless register is 0.
+func (r *ClipboardRegisters) Push(clip adraw.Grid[adraw.Cell], register rune) {
+	if register != 0 {
+		r.Named[register] = clip
+	}
+	r.History = append([]adraw.Grid[adraw.Cell]{clip}, r.History...)
+	if len(r.History) > CLIPBOARD_HISTORY_SIZE {
+		r.History = r.History[:CLIPBOARD_HISTORY_SIZE]
+	}
+}
+
+// A grid in the cell encoding of the binary file format.
+type storedGrid struct {
+	Width  int      `json:"w"`
+	Height int      `json:"h"`
+	Cells  []uint16 `json:"cells"`
+}
+
+type storedRegisters struct {
+	Named   map[string]storedGrid `json:"named"`
+	History []storedGrid          `json:"history"`
+}
+
+func encodeGrid(g adraw.Grid[adraw.Cell]) storedGrid {
+	s := storedGrid{Width: g.Width, Height: g.Height}
+	for y := range g.Height {
+		for x := range g.Width {
+			c := g.MustGet(x, y)
+			s.Cells = append(s.Cells, adraw.Encode(&c))
+		}
+	}
+	return s
+}
+
+func decodeGrid(s storedGrid) (adraw.Grid[adraw.Cell], error) {
+	if s.Width <= 0 || s.Height <= 0 || len(s.Cells) != s.Width*s.Height {
+		return adraw.Grid[adraw.Cell]{}, fmt.Errorf("invalid %d x %d grid with %d cells", s.Width, s.Height, len(s.Cells))
+	}
+	return adraw.MakeGridWith(s.Width, s.Height, func(x, y int) adraw.Cell {
+		var c adraw.Cell
+		adraw.Decode(s.Cells[y*s.Width+x], &c)
+		return c
+	}), nil
+}
+
+func (r *ClipboardRegisters) MarshalJSON() ([]byte, error) {
+	s := storedRegisters{Named: map[string]storedGrid{}, History: []storedGrid{}}
+	for reg, g := range r.Named {
+		s.Named[string(reg)] = encodeGrid(g)
+	}
+	for _, g := range r.History {
+		s.History = append(s.History, encodeGrid(g))
+	}
+	return json.Marshal(s)
+}
+
+func (r *ClipboardRegisters) UnmarshalJSON(data []byte) error {
+	var s storedRegisters
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	res := NewClipboardRegisters()
+	for reg, sg := range s.Named {
+		if len([]rune(reg)) != 1 || !strings.Contains(MACRO_REGISTERS, reg) {
+			return fmt.Errorf("invalid register %q", reg)
+		}
+		g, err := decodeGrid(sg)
+		if err != nil {
+			return fmt.Errorf("register %s: %w", reg, err)
+		}
+		res.Named[[]rune(reg)[0]] = g
+	}
+	for i, sg := range s.History {
+		if i == CLIPBOARD_HISTORY_SIZE {
+			break
+		}
+		g, err := decodeGrid(sg)
+		if err != nil {
+			return fmt.Errorf("history entry %d: %w", i+1, err)
+		}
+		res.History = append(res.History, g)
+	}
+	*r = *res
+	return nil
+}
+
+// Reads the registers saved in registers.json in the configuration directory. A missing
+// file is not an error.
+func LoadRegistersFile() (*ClipboardRegisters, error) {
+	path, err := ConfigPath("registers.json")
+	if err != nil {
+		return NewClipboardRegisters(), err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewClipboardRegisters(), nil
+	} else if err != nil {
+		return NewClipboardRegisters(), err
+	}
+
+	r := NewClipboardRegisters()
+	if err := json.Unmarshal(data, r); err != nil {
+		return NewClipboardRegisters(), fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Writes the registers to registers.json in the configuration directory.
+func (r *ClipboardRegisters) SaveFile() error {
+	path, err := ConfigPath("registers.json")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Copies the selection to the clipboard, recording it in the history and in the register
+// chosen beforehand, if any. Returns false if nothing is selected.
+func (m *Editor) SetClipboard() bool {
+	canvas := m.CurrentCanvas()
+	if !canvas.HasSelection() {
+		m.notification.PushNotification("", "Nothing selected", NotificationWarning)
+		return false
+	}
+	m.clipboard = canvas.CopySelection()
+	m.registers.Push(m.clipboard, m.pendingRegister)
+	m.pendingRegister = 0
+	m.saveRegisters()
+	return true
+}
+
+// Replaces the clipboard with the register chosen beforehand, if any. Returns false if that
+// register is empty.
+func (m *Editor) UsePendingRegister() bool {
+	reg := m.pendingRegister
+	if reg == 0 {
+		return true
+	}
+	m.pendingRegister = 0
+	clip, ok := m.registers.Named[reg]
+	if !ok {
+		m.notification.PushNotification("", fmt.Sprintf("Register %c is empty", reg), NotificationWarning)
+		return false
+	}
+	m.clipboard = clip
+	return true
+}
+
+func (m *Editor) saveRegisters() {
+	if !m.config.PersistRegisters || m.app.DryRun {
+		return
+	}
+	if err := m.registers.SaveFile(); err != nil {
+		m.app.Logger.Printf("Error saving registers: %v", err)
+		m.notification.PushNotification("Error saving registers", err.Error(), NotificationCritical)
+	}
+}
+
+// An entry of the register picker.
+type registerEntry struct {
+	label string
+	clip  adraw.Grid[adraw.Cell]
+}
+
+// Modal tool listing the clipboard history and the named registers with a preview of the
+// selected entry. Picking an entry loads it into the clipboard and switches to the stamp
+// tool.
+type RegisterPickerTool struct {
+	entries  []registerEntry
+	selected int
+}
+
+var (
+	_ Tool = &RegisterPickerTool{}
+)
+
+func NewRegisterPickerTool(r *ClipboardRegisters) *RegisterPickerTool {
+	t := &RegisterPickerTool{}
+	for i, clip := range r.History {
+		t.entries = append(t.entries, registerEntry{fmt.Sprintf("%d", i+1), clip})
+	}
+	for _, reg := range MACRO_REGISTERS {
+		if clip, ok := r.Named[reg]; ok {
+			t.entries = append(t.entries, registerEntry{fmt.Sprintf("\"%c", reg), clip})
+		}
+	}
+	return t
+}
+
+func (e *RegisterPickerTool) pick(m *Editor) {
+	m.ClearModalTool()
+	m.clipboard = e.entries[e.selected].clip
+	m.SetTool(&StampTool{})
+}
+
+func (e *RegisterPickerTool) HandleEvent(m *Editor, event tcell.Event) {
+	ev, ok := event.(*tcell.EventKey)
+	if !ok || len(e.entries) == 0 {
+		return
+	}
+
+	switch ev.Key() {
+	case tcell.KeyUp:
+		e.selected = max(0, e.selected-1)
+	case tcell.KeyDown:
+		e.selected = min(len(e.entries)-1, e.selected+1)
+	case tcell.KeyEnter:
+		e.pick(m)
+	case tcell.KeyRune:
+		switch r := ev.Rune(); r {
+		case 'k':
+			e.selected = max(0, e.selected-1)
+		case 'j':
+			e.selected = min(len(e.entries)-1, e.selected+1)
+		default:
+			// Jump to a register by name, or a history entry by number
+			label := fmt.Sprintf("\"%c", r)
+			if r >= '1' && r <= '9' {
+				label = string(r)
+			}
+			for i, entry := range e.entries {
+				if entry.label == label {
+					e.selected = i
+				}
+			}
+		}
+	}
+}
+
+func (e *RegisterPickerTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
+	r := adraw.Area{
+		Width:  min(70, w-2),
+		Height: min(CLIPBOARD_HISTORY_SIZE+len(MACRO_REGISTERS)+3, h-2),
+	}
+	r.X = x + (w-r.Width)/2
+	r.Y = y + (h-r.Height)/2
+	bb := adraw.Area{
+		X:      r.X - 1,
+		Y:      r.Y - 1,
+		Width:  r.Width + 2,
+		Height: r.Height + 2,
+	}
+	BorderBox(p, bb, tcell.StyleDefault)
+	FillRegion(p, r.X, r.Y, r.Width, r.Height, ' ', tcell.StyleDefault)
+	crop := &CropPainter{p: p, area: r}
+
+	SetString(crop, r.X, r.Y, "Clipboard registers", tcell.StyleDefault)
+	SetString(crop, r.X, r.Y+r.Height-1, "up/down, name: select  enter: paste  esc: close",
+		tcell.StyleDefault.Foreground(tcell.ColorGray))
+	if len(e.entries) == 0 {
+		SetString(crop, r.X, r.Y+2, "nothing copied yet", tcell.StyleDefault)
+		return
+	}
+
+	// List on the left, preview of the selected entry on the right
+	const listWidth = 16
+	listHeight := r.Height - 3
+	first := max(0, min(e.selected-listHeight/2, len(e.entries)-listHeight))
+	for i := range listHeight {
+		idx := first + i
+		if idx >= len(e.entries) {
+			break
+		}
+		entry := e.entries[idx]
+		st := tcell.StyleDefault
+		if idx == e.selected {
+			st = st.Reverse(true)
+		}
+		line := fmt.Sprintf("%-3s %d x %d", entry.label, entry.clip.Width, entry.clip.Height)
+		SetString(crop, r.X, r.Y+2+i, fmt.Sprintf("%-*s", listWidth-1, line), st)
+	}
+
+	preview := &CropPainter{
+		p: crop,
+		area: adraw.Area{
+			X:      r.X + listWidth,
+			Y:      r.Y + 2,
+			Width:  r.Width - listWidth,
+			Height: listHeight,
+		},
+	}
+	clip := e.entries[e.selected].clip
+	for cy := range clip.Height {
+		for cx := range clip.Width {
+			c := clip.MustGet(cx, cy)
+			v := c.Value
+			if v == 0 {
+				v = ' '
+			}
+			preview.SetByte(r.X+listWidth+cx, r.Y+2+cy, v, m.palette.Style(c.Style))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestRegistersRoundTrip(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("x")
+	h.Drag(pos(0, 0), pos(2, 0))
+	h.Press("ctrl+r")
+	h.Drag(pos(0, 0), pos(2, 0), pos(2, 1), pos(0, 1))
+	h.Press("alt+\"")
+	h.Type("q")
+	h.Press("ctrl+c")
+
+	r := h.Editor.registers
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewClipboardRegisters()
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatal(err)
+	}
+	clip, ok := loaded.Named['q']
+	if !ok || len(loaded.History) != 1 {
+		t.Fatalf("loaded registers %+v do not match", loaded)
+	}
+	if clip.Width != 3 || clip.Height != 2 || clip.MustGet(1, 0).Value != 'x' {
+		t.Errorf("register q holds a %d x %d grid", clip.Width, clip.Height)
+	}
+}
+
+func TestPasteFromRegister(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("a")
+	h.Click(pos(0, 0))
+	h.Type("b")
+	h.Click(pos(0, 1))
+
+	selectCell := func(x, y int) {
+		h.Press("ctrl+r")
+		h.Drag(pos(x, y), pos(x, y))
+	}
+
+	selectCell(0, 0)
+	h.Press("alt+\"")
+	h.Type("a")
+	h.Press("ctrl+c")
+
+	selectCell(0, 1)
+	h.Press("ctrl+c")
+	h.Press("ctrl+a")
+
+	clipValue := func() byte {
+		return h.Editor.clipboard.MustGet(0, 0).Value
+	}
+
+	// The named register, rather than the last copy
+	h.Press("alt+\"")
+	h.Type("a")
+	h.Press("ctrl+v")
+	if v := clipValue(); v != 'a' {
+		t.Errorf("pasting register a pastes %q", v)
+	}
+
+	// The picker lists history newest first: 1 is the copy of b, 2 the copy of a
+	h.Press("alt+v")
+	h.Type("1")
+	h.Press("enter")
+	if v := clipValue(); v != 'b' {
+		t.Errorf("picking history entry 1 pastes %q", v)
+	}
+	h.Press("alt+v")
+	h.Type("2")
+	h.Press("enter")
+	if v := clipValue(); v != 'a' {
+		t.Errorf("picking history entry 2 pastes %q", v)
+	}
+	if _, ok := h.Editor.currentTool.(*StampTool); !ok {
+		t.Errorf("picking an entry did not switch to the stamp tool")
+	}
+}
+
+func TestCopyWithoutSelection(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Press("ctrl+c")
+	if len(h.Editor.registers.History) != 0 {
+		t.Error("copying without a selection added to the history")
+	}
+	h.Send(tcell.NewEventKey(tcell.KeyCtrlX, 0, tcell.ModNone))
+	h.AssertCanvasSize(20, 10)
+}
@@ -3,13 +3,12 @@ package main
 import (
 	"fmt"
 
+	"github.com/Fekinox/ascii-draw/adraw"
 	"github.com/gdamore/tcell/v2"
 	"github.com/mattn/go-runewidth"
 )
 
 var (
-	Screen tcell.Screen
-
 	Condition = &runewidth.Condition{
 		EastAsianWidth:     true,
 		StrictEmojiNeutral: true,
@@ -23,21 +22,27 @@ type Painter interface {
 	GetContent(x, y int) (rune, tcell.Style)
 }
 
-type DefaultPainter struct{}
+// Paints directly onto a screen.
+type DefaultPainter struct {
+	Screen tcell.Screen
+}
 
 type CropPainter struct {
 	p            Painter
-	offsetBefore Position
-	area         Area
-	offsetAfter  Position
+	offsetBefore adraw.Position
+	area         adraw.Area
+	offsetAfter  adraw.Position
 }
 
-var (
-	Paint DefaultPainter
-)
+// Records the position of every cell painted through it, so that the caller knows which
+// cells need to be restored once the painted content goes away.
+type DamagePainter struct {
+	p       Painter
+	Touched []adraw.Position
+}
 
 func (d DefaultPainter) SetByte(x, y int, v byte, style tcell.Style) {
-	Screen.SetContent(x, y, rune(v), nil, style)
+	d.Screen.SetContent(x, y, rune(v), nil, style)
 }
 
 func (d DefaultPainter) SetRune(
@@ -46,17 +51,17 @@ func (d DefaultPainter) SetRune(
 	combining []rune,
 	style tcell.Style,
 ) {
-	Screen.SetContent(x, y, v, combining, style)
+	d.Screen.SetContent(x, y, v, combining, style)
 }
 
 func (d DefaultPainter) GetContent(x, y int) (rune, tcell.Style) {
-	rune, _, style, _ := Screen.GetContent(x, y)
+	rune, _, style, _ := d.Screen.GetContent(x, y)
 	return rune, style
 }
 
 func (d DefaultPainter) SetStyle(x, y int, style tcell.Style) {
-	pri, com, _, _ := Screen.GetContent(x, y)
-	Screen.SetContent(x, y, pri, com, style)
+	pri, com, _, _ := d.Screen.GetContent(x, y)
+	d.Screen.SetContent(x, y, pri, com, style)
 }
 
 func (a *CropPainter) SetByte(x, y int, v byte, style tcell.Style) {
@@ -88,13 +93,36 @@ func (a *CropPainter) SetStyle(x, y int, style tcell.Style) {
 func (a *CropPainter) GetContent(x, y int) (rune, tcell.Style) {
 	xx, yy := x+a.offsetBefore.X, y+a.offsetBefore.Y
 	if a.area.Contains(xx, yy) {
-		rune, _, style, _ := Screen.GetContent(xx, yy)
-		return rune, style
+		return a.p.GetContent(xx+a.offsetAfter.X, yy+a.offsetAfter.Y)
 	}
 
 	return 0, tcell.StyleDefault
 }
 
+func (d *DamagePainter) SetByte(x, y int, v byte, style tcell.Style) {
+	d.Touched = append(d.Touched, adraw.Position{X: x, Y: y})
+	d.p.SetByte(x, y, v, style)
+}
+
+func (d *DamagePainter) SetRune(
+	x, y int,
+	v rune,
+	combining []rune,
+	style tcell.Style,
+) {
+	d.Touched = append(d.Touched, adraw.Position{X: x, Y: y})
+	d.p.SetRune(x, y, v, combining, style)
+}
+
+func (d *DamagePainter) SetStyle(x, y int, style tcell.Style) {
+	d.Touched = append(d.Touched, adraw.Position{X: x, Y: y})
+	d.p.SetStyle(x, y, style)
+}
+
+func (d *DamagePainter) GetContent(x, y int) (rune, tcell.Style) {
+	return d.p.GetContent(x, y)
+}
+
 type Span struct {
 	Contents string
 	Style    tcell.Style
@@ -147,7 +175,7 @@ func SetCenteredSpans(p Painter, x, y int, spans ...Span) {
 	}
 }
 
-func SetGrid(p Painter, x, y int, grid Grid[rune], style tcell.Style) {
+func SetGrid(p Painter, x, y int, grid adraw.Grid[rune], style tcell.Style) {
 	for dy := 0; dy < grid.Height; dy++ {
 		for dx := 0; dx < grid.Width; dx++ {
 			p.SetRune(
@@ -204,7 +232,7 @@ func ShowResizeScreen(p Painter, w, h int, style tcell.Style) {
 	)
 }
 
-func BorderBox(p Painter, area Area, style tcell.Style) {
+func BorderBox(p Painter, area adraw.Area, style tcell.Style) {
 	// Draw corners
 	p.SetRune(area.X, area.Y, tcell.RuneULCorner, nil, style)
 	p.SetRune(area.X+area.Width-1, area.Y, tcell.RuneURCorner, nil, style)
@@ -300,7 +328,7 @@ func DrawColorPickerState(
 	copyChar, copyFg, copyBg bool,
 	hoverChar byte, hoverFg, hoverBg tcell.Color,
 ) {
-	rect := Area{
+	rect := adraw.Area{
 		X:      x,
 		Y:      y - 5,
 		Width:  8,
@@ -326,13 +354,13 @@ func DrawColorPickDragIndicator(
 	p Painter,
 	x, y, orx, ory int,
 ) {
-	origRect := Area{
+	origRect := adraw.Area{
 		X:      orx - 3,
 		Y:      ory - 2,
 		Width:  7,
 		Height: 5,
 	}
-	indRect := Area{
+	indRect := adraw.Area{
 		X:      x + 2,
 		Y:      y - 1,
 		Width:  11,
@@ -402,6 +430,7 @@ func DrawColorSelector(
 	p Painter,
 	x, y int,
 	colorSelectState ColorSelectState,
+	palette []tcell.Color,
 ) {
 	if colorSelectState == ColorSelectFg {
 		SetString(p, x+1, y, "fg: ", tcell.StyleDefault)
@@ -411,7 +440,7 @@ func DrawColorSelector(
 
 	for i := range 8 {
 		xx, yy := i%4, i/4
-		color := tcell.Color(i) + tcell.ColorValid
+		color := palette[i]
 
 		var st tcell.Style
 		if colorSelectState == ColorSelectFg {
@@ -431,7 +460,7 @@ func DrawColorSelector(
 
 	for i := range 8 {
 		xx, yy := i%4, i/4
-		color := tcell.Color(i+8) + tcell.ColorValid
+		color := palette[i+8]
 
 		var st tcell.Style
 		if colorSelectState == ColorSelectFg {
@@ -3,9 +3,25 @@ package main
 import (
 	"fmt"
 
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
 	"github.com/gdamore/tcell/v2"
 )
 
+func init() {
+	RegisterTool(ToolInfo{
+		Name:        "resize",
+		Action:      action.Resize,
+		Description: "change the dimensions of the canvas",
+		Gestures: []string{
+			"click and drag outside the region: draw new bounds",
+			"click and drag inside the region: move the bounds",
+			"click and drag a handle: move an edge or corner",
+			"enter: commit the resize",
+		},
+	})
+}
+
 type ResizeToolState int
 type ResizeToolEdge int
 
@@ -24,14 +40,14 @@ const (
 )
 
 type ResizeToolHandle struct {
-	pos   Position
+	pos   adraw.Position
 	edges ResizeToolEdge
 }
 
 type ResizeTool struct {
 	state       ResizeToolState
-	dims        Area
-	stagingDims Area
+	dims        adraw.Area
+	stagingDims adraw.Area
 	handles     [8]ResizeToolHandle
 
 	origX      int
@@ -75,51 +91,51 @@ func (r *ResizeTool) InitHandles() {
 	r.RepositionHandles(r.dims)
 }
 
-func (r *ResizeTool) RepositionHandles(d Area) {
+func (r *ResizeTool) RepositionHandles(d adraw.Area) {
 	// top left
-	r.handles[0].pos = Position{
+	r.handles[0].pos = adraw.Position{
 		X: d.X - 2,
 		Y: d.Y - 2,
 	}
 	// top
-	r.handles[1].pos = Position{
+	r.handles[1].pos = adraw.Position{
 		X: d.X + d.Width/2 + 1,
 		Y: d.Y - 2,
 	}
 	// top right
-	r.handles[2].pos = Position{
+	r.handles[2].pos = adraw.Position{
 		X: d.X + d.Width + 3,
 		Y: d.Y - 2,
 	}
 	// right
-	r.handles[3].pos = Position{
+	r.handles[3].pos = adraw.Position{
 		X: d.X + d.Width + 3,
 		Y: d.Y + d.Height/2 + 1,
 	}
 	// bottom right
-	r.handles[4].pos = Position{
+	r.handles[4].pos = adraw.Position{
 		X: d.X + d.Width + 3,
 		Y: d.Y + d.Height + 3,
 	}
 	// bottom
-	r.handles[5].pos = Position{
+	r.handles[5].pos = adraw.Position{
 		X: d.X + d.Width/2 + 1,
 		Y: d.Y + d.Height + 3,
 	}
 	// bottom left
-	r.handles[6].pos = Position{
+	r.handles[6].pos = adraw.Position{
 		X: d.X - 2,
 		Y: d.Y + d.Height + 3,
 	}
 	// left
-	r.handles[7].pos = Position{
+	r.handles[7].pos = adraw.Position{
 		X: d.X - 2,
 		Y: d.Y + d.Height/2 + 1,
 	}
 }
 
-func (r *ResizeTool) SetDimsFromSelection(b *Buffer) {
-	if !b.activeSelection {
+func (r *ResizeTool) SetDimsFromSelection(b *adraw.Buffer) {
+	if !b.HasSelection() {
 		r.dims.X, r.dims.Y = 0, 0
 		r.dims.Width, r.dims.Height = b.Data.Width, b.Data.Height
 		return
@@ -153,10 +169,10 @@ func (r *ResizeTool) HandleEvent(m *Editor, event tcell.Event) {
 				r.stagingDims = r.dims
 				r.origX, r.origY = cx, cy
 
-				minDist := SquaredDistance(cx, cy, r.handles[0].pos.X, r.handles[0].pos.Y)
+				minDist := adraw.SquaredDistance(cx, cy, r.handles[0].pos.X, r.handles[0].pos.Y)
 				var argmin int
 				for j, h := range r.handles {
-					d := SquaredDistance(cx, cy, h.pos.X, h.pos.Y)
+					d := adraw.SquaredDistance(cx, cy, h.pos.X, h.pos.Y)
 					if d < minDist {
 						minDist = d
 						argmin = j
@@ -245,7 +261,7 @@ func (r *ResizeTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
 	SetString(p, x+m.sx, y+m.sy-1, "Resize Tool", tcell.StyleDefault)
 	crop := &CropPainter{
 		p: p,
-		area: Area{
+		area: adraw.Area{
 			X:      m.sx,
 			Y:      m.sy,
 			Width:  m.sw,
@@ -263,7 +279,7 @@ func (r *ResizeTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
 	if r.state != ResizeToolNone {
 		d = r.stagingDims
 	}
-	a := Area{
+	a := adraw.Area{
 		X:      x + m.sx + ox + d.X - 1,
 		Y:      y + m.sy + oy + d.Y - 1,
 		Width:  d.Width + 2,
@@ -1,12 +1,27 @@
 package main
 
-import "github.com/gdamore/tcell/v2"
+import (
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
+
+func init() {
+	RegisterTool(ToolInfo{
+		Name:        "lasso",
+		Action:      action.Lasso,
+		Description: "select a freeform region",
+		Gestures: []string{
+			"click and drag: draw the outline of the selection",
+		},
+	})
+}
 
 type LassoTool struct {
 	isLassoing  bool
-	lassoPoints []Position
-	topLeft     Position
-	mask        Grid[bool]
+	lassoPoints []adraw.Position
+	topLeft     adraw.Position
+	mask        adraw.Grid[bool]
 }
 
 func (l *LassoTool) HandleEvent(m *Editor, event tcell.Event) {
@@ -18,14 +33,14 @@ func (l *LassoTool) HandleEvent(m *Editor, event tcell.Event) {
 				l.isLassoing = true
 				l.lassoPoints = nil
 			}
-			p := Position{X: cx, Y: cy}
+			p := adraw.Position{X: cx, Y: cy}
 			if len(l.lassoPoints) == 0 || l.lassoPoints[len(l.lassoPoints)-1] != p {
 				l.lassoPoints = append(l.lassoPoints, p)
 			}
 		} else if l.isLassoing {
 			m.Stage()
 			l.isLassoing = false
-			topLeft, mask := CreateMask(l.lassoPoints)
+			topLeft, mask := adraw.CreateMask(l.lassoPoints)
 			// convert from canvas to screen coords
 			topLeft.X -= m.sx + m.offsetX
 			topLeft.Y -= m.sy + m.offsetY
@@ -41,7 +56,7 @@ func (l *LassoTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
 		j := len(l.lassoPoints) - 1
 		for i, p1 := range l.lassoPoints {
 			p2 := l.lassoPoints[j]
-			points := LinePositions(p1.X, p1.Y, p2.X, p2.Y)
+			points := adraw.LinePositions(p1.X, p1.Y, p2.X, p2.Y)
 			for _, ps := range points {
 				p.SetByte(ps.X, ps.Y, '#', tcell.StyleDefault)
 			}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+)
+
+// A field of the configuration that can be edited in the settings dialog.
+type setting struct {
+	name string
+	get  func(c *Config) string
+	set  func(c *Config, s string) error
+}
+
+func intSetting(name string, field func(c *Config) *int) setting {
+	return setting{
+		name: name,
+		get: func(c *Config) string {
+			return strconv.Itoa(*field(c))
+		},
+		set: func(c *Config, s string) error {
+			v, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return fmt.Errorf("%q is not a number", s)
+			}
+			*field(c) = v
+			return nil
+		},
+	}
+}
+
+func boolSetting(name string, field func(c *Config) *bool) setting {
+	return setting{
+		name: name,
+		get: func(c *Config) string {
+			if *field(c) {
+				return "on"
+			}
+			return "off"
+		},
+		set: func(c *Config, s string) error {
+			switch strings.ToLower(strings.TrimSpace(s)) {
+			case "on", "true", "yes":
+				*field(c) = true
+			case "off", "false", "no":
+				*field(c) = false
+			default:
+				return fmt.Errorf("%q is not on or off", s)
+			}
+			return nil
+		},
+	}
+}
+
+func stringSetting(name string, field func(c *Config) *string) setting {
+	return setting{
+		name: name,
+		get: func(c *Config) string {
+			return *field(c)
+		},
+		set: func(c *Config, s string) error {
+			*field(c) = s
+			return nil
+		},
+	}
+}
+
+var settings = []setting{
+	intSetting("canvas width", func(c *Config) *int { return &c.Width }),
+	intSetting("canvas height", func(c *Config) *int { return &c.Height }),
+	stringSetting("brush char", func(c *Config) *string { return &c.BrushCharacter }),
+	intSetting("brush radius", func(c *Config) *int { return &c.BrushRadius }),
+	intSetting("max brush radius", func(c *Config) *int { return &c.MaxBrushRadius }),
+	stringSetting("brush shape", func(c *Config) *string { return &c.BrushShape }),
+	stringSetting("ramp", func(c *Config) *string { return &c.Ramp }),
+	intSetting("spray radius", func(c *Config) *int { return &c.SprayRadius }),
+	intSetting("spray density", func(c *Config) *int { return &c.SprayDensity }),
+	{
+		name: "spray seed",
+		get: func(c *Config) string {
+			return strconv.FormatUint(c.SpraySeed, 10)
+		},
+		set: func(c *Config, s string) error {
+			v, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return fmt.Errorf("%q is not a number", s)
+			}
+			c.SpraySeed = v
+			return nil
+		},
+	},
+	{
+		name: "color ramp",
+		get: func(c *Config) string {
+			return strings.Join(c.ColorRamp, " ")
+		},
+		set: func(c *Config, s string) error {
+			c.ColorRamp = strings.Fields(s)
+			return nil
+		},
+	},
+	{
+		name: "notification secs",
+		get: func(c *Config) string {
+			return strconv.FormatFloat(c.NotificationDuration, 'f', -1, 64)
+		},
+		set: func(c *Config, s string) error {
+			v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return fmt.Errorf("%q is not a number", s)
+			}
+			c.NotificationDuration = v
+			return nil
+		},
+	},
+	stringSetting("save directory", func(c *Config) *string { return &c.SaveDirectory }),
+	stringSetting("log file", func(c *Config) *string { return &c.LogFile }),
+	stringSetting("clipboard", func(c *Config) *string { return &c.Clipboard }),
+	boolSetting("persist registers", func(c *Config) *bool { return &c.PersistRegisters }),
+	{
+		name: "palette",
+		get: func(c *Config) string {
+			return strings.Join(c.Palette, " ")
+		},
+		set: func(c *Config, s string) error {
+			c.Palette = strings.Fields(s)
+			return nil
+		},
+	},
+	stringSetting("palette file", func(c *Config) *string { return &c.PaletteFile }),
+}
+
+// Modal dialog that edits the configuration. Every accepted change is applied to the editor
+// and written back to the configuration file.
+type SettingsTool struct {
+	config   *Config
+	selected int
+	editing  bool
+	text     TextWidget
+	err      string
+}
+
+var (
+	_ Tool = &SettingsTool{}
+)
+
+func NewSettingsTool(c *Config) *SettingsTool {
+	return &SettingsTool{config: c}
+}
+
+func (e *SettingsTool) HandleEvent(m *Editor, event tcell.Event) {
+	ev, ok := event.(*tcell.EventKey)
+	if !ok {
+		return
+	}
+
+	if e.editing {
+		e.text.HandleEvent(ev)
+		return
+	}
+
+	switch ev.Key() {
+	case tcell.KeyUp:
+		e.selected = max(0, e.selected-1)
+	case tcell.KeyDown:
+		e.selected = min(len(settings)-1, e.selected+1)
+	case tcell.KeyEnter:
+		e.startEditing(m)
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'k':
+			e.selected = max(0, e.selected-1)
+		case 'j':
+			e.selected = min(len(settings)-1, e.selected+1)
+		}
+	}
+}
+
+func (e *SettingsTool) startEditing(m *Editor) {
+	s := settings[e.selected]
+	e.editing = true
+	e.err = ""
+	e.text = TextWidget{Active: true}
+	e.text.SetContents(s.get(e.config))
+	e.text.OnSubmit = func(v string) {
+		c := *e.config
+		c.Palette = slices.Clone(c.Palette)
+		if err := s.set(&c, v); err != nil {
+			e.err = err.Error()
+			return
+		}
+		if err := c.Validate(); err != nil {
+			e.err = err.Error()
+			return
+		}
+
+		e.config = &c
+		e.editing = false
+		m.ApplyConfig(&c)
+		if m.app.DryRun {
+			return
+		}
+		if err := c.Save(); err != nil {
+			m.app.Logger.Printf("Error saving config: %v", err)
+			m.notification.PushNotification("Error saving config", err.Error(), NotificationCritical)
+		}
+	}
+}
+
+func (e *SettingsTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
+	r := adraw.Area{
+		Width:  60,
+		Height: len(settings) + 4,
+	}
+	r.X = x + (w-r.Width)/2
+	r.Y = y + (h-r.Height)/2
+	bb := adraw.Area{
+		X:      r.X - 1,
+		Y:      r.Y - 1,
+		Width:  r.Width + 2,
+		Height: r.Height + 2,
+	}
+	BorderBox(p, bb, tcell.StyleDefault)
+	FillRegion(p, r.X, r.Y, r.Width, r.Height, ' ', tcell.StyleDefault)
+	SetCenteredString(p, r.X+r.Width/2, r.Y, "Settings", tcell.StyleDefault)
+
+	const valueCol = 20
+	for i, s := range settings {
+		yy := r.Y + 2 + i
+		st := tcell.StyleDefault
+		if i == e.selected {
+			st = st.Reverse(true)
+		}
+		SetString(p, r.X, yy, s.name, st)
+
+		value := adraw.Area{X: r.X + valueCol, Y: yy, Width: r.Width - valueCol, Height: 1}
+		crop := &CropPainter{p: p, area: value}
+		if e.editing && i == e.selected {
+			e.text.Draw(crop, value.X, value.Y, value.Width, 1, lag)
+		} else {
+			SetString(crop, value.X, value.Y, s.get(e.config), tcell.StyleDefault)
+		}
+	}
+
+	footer := "up/down: select  enter: edit  esc: close"
+	st := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	if e.err != "" {
+		footer = e.err
+		st = tcell.StyleDefault.Foreground(tcell.ColorRed)
+	} else if e.editing {
+		footer = "enter: apply and save  esc: close"
+	}
+	crop := &CropPainter{p: p, area: adraw.Area{X: r.X, Y: r.Y + r.Height - 1, Width: r.Width, Height: 1}}
+	SetString(crop, r.X, r.Y+r.Height-1, footer, st)
+}
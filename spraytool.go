@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
+
+func init() {
+	RegisterTool(ToolInfo{
+		Name:        "spray",
+		Action:      action.Spray,
+		Description: "scatter the brush cell at random around the cursor",
+		Gestures: []string{
+			"click and drag: spray",
+			"right click and drag: spray blanks to erase",
+			"alt+=, alt+-: change the spray radius",
+		},
+	})
+}
+
+type SprayOptions struct {
+	Radius int
+	// Share of the circle, in percent, painted by each burst.
+	Density int
+	// If not 0, every stroke starts from this seed, so that the same stroke sprays the
+	// same cells.
+	Seed uint64
+}
+
+func (o SprayOptions) String() string {
+	s := fmt.Sprintf("radius %d, density %d%%", o.Radius, o.Density)
+	if o.Seed != 0 {
+		s += fmt.Sprintf(", seed %d", o.Seed)
+	}
+	return s
+}
+
+// Circle that the spray tool paints in.
+func (o SprayOptions) Mask() adraw.Grid[bool] {
+	return adraw.BrushMask(adraw.BrushCircle, 2*o.Radius+1)
+}
+
+// Number of cells painted by a burst.
+func (o SprayOptions) BurstSize(mask adraw.Grid[bool]) int {
+	var area int
+	for y := range mask.Height {
+		for x := range mask.Width {
+			if mask.MustGet(x, y) {
+				area++
+			}
+		}
+	}
+	return max(1, area*o.Density/100)
+}
+
+// Number of ticks between the bursts sprayed while the mouse is held still.
+const SPRAY_BURST_TICKS = 4
+
+func newRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
+// Sprays a burst on every mouse event while the button is held, and every few ticks while
+// the mouse is held still. Bursts are tied to events and counted ticks rather than time, so
+// that replaying a recording sprays the same cells.
+type SprayTool struct {
+	isDragging bool
+	// Whether the current stroke erases.
+	erasing bool
+	step    int
+	// Ticks since the last burst.
+	ticks int
+}
+
+var (
+	_ Tool = &SprayTool{}
+)
+
+func (s *SprayTool) HandleEvent(m *Editor, event tcell.Event) {
+	switch ev := event.(type) {
+	case *tcell.EventMouse:
+		if ev.Buttons()&(tcell.Button1|ERASE_BUTTONS) != 0 {
+			if !s.isDragging {
+				s.isDragging = true
+				s.erasing = ev.Buttons()&tcell.Button1 == 0
+				s.step = 0
+				if m.spray.Seed != 0 {
+					m.rng = newRand(m.spray.Seed)
+				}
+			}
+			s.burst(m)
+		} else if s.isDragging {
+			s.isDragging = false
+			m.Commit()
+		}
+	case *EventTick:
+		if !s.isDragging {
+			return
+		}
+		s.ticks++
+		if s.ticks >= SPRAY_BURST_TICKS {
+			s.burst(m)
+		}
+	}
+}
+
+func (s *SprayTool) burst(m *Editor) {
+	m.Stage()
+	mask := m.spray.Mask()
+	cell, lockMask := m.brushCell(s.step), m.lockMask
+	if s.erasing {
+		cell, lockMask = eraseCell, m.EraseLockMask()
+	}
+	m.stagingCanvas.Spray(
+		mask, m.cursorCanvasPosition(), m.spray.BurstSize(mask), m.rng,
+		cell, lockMask,
+	)
+	s.step++
+	s.ticks = 0
+}
+
+func (s *SprayTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
+	SetString(p, x+m.sx, y+m.sy-1, fmt.Sprintf("Spray Tool (%s)", m.spray), tcell.StyleDefault)
+
+	// Outline of the circle around the cursor
+	mask := m.spray.Mask()
+	cx, cy := m.cursorX+m.sx, m.cursorY+m.sy
+	left, top := cx-mask.Width/2, cy-mask.Height/2
+	st := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	for dy := range mask.Height {
+		for dx := range mask.Width {
+			if !mask.MustGet(dx, dy) {
+				continue
+			}
+			edge := false
+			for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+				if in, ok := mask.Get(dx+d[0], dy+d[1]); !ok || !in {
+					edge = true
+				}
+			}
+			if edge {
+				p.SetByte(left+dx, top+dy, '.', st)
+			}
+		}
+	}
+	cell := m.brushCell(s.step)
+	p.SetByte(cx, cy, cell.Value, m.palette.Style(cell.Style))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestSpraySeed(t *testing.T) {
+	h := NewHarness(t, 30, 15)
+	h.RunExLine("spray seed 42")
+	h.RunExLine("spray radius 3")
+	h.Press("alt+a")
+	h.Type("*")
+	h.Drag(pos(10, 7), pos(10, 7), pos(10, 7))
+	first := h.CanvasText()
+	if strings.Count(first, "*") < 3 {
+		t.Fatalf("spraying painted too little:\n%s", first)
+	}
+
+	h.Press("ctrl+z")
+	h.Drag(pos(10, 7), pos(10, 7), pos(10, 7))
+	if got := h.CanvasText(); got != first {
+		t.Errorf("the same stroke with the same seed sprayed\n%s\nthen\n%s", first, got)
+	}
+
+	// Nothing lands outside the circle
+	for y, line := range strings.Split(first, "\n") {
+		for x, c := range line {
+			dx, dy := x-10, y-7
+			if c == '*' && dx*dx+dy*dy > 3*3+3 {
+				t.Errorf("sprayed (%d, %d), outside the circle", x, y)
+			}
+		}
+	}
+}
+
+func TestSprayIsClipped(t *testing.T) {
+	h := NewHarness(t, 30, 15)
+	h.Type("o")
+	h.Click(pos(11, 7))
+	h.RunExLine("spray density 100")
+	h.RunExLine("select rect 10 5 5 5")
+	h.Press("alt+a")
+	h.Press("alt+1")
+	h.Type("*")
+	for range 20 {
+		h.Drag(pos(10, 7))
+	}
+
+	// Only the one filled cell inside the selection is painted under alpha lock
+	h.AssertCanvas(`
+
+
+
+
+
+
+
+           *`)
+}
+
+func TestSprayWhileHeld(t *testing.T) {
+	h := NewHarness(t, 30, 15)
+	h.RunExLine("spray seed 7")
+	h.RunExLine("spray density 5")
+	h.Press("alt+a")
+	h.Type("*")
+	h.Mouse(pos(10, 7), tcell.Button1)
+	if !h.Editor.Animating() {
+		t.Fatal("the editor does not ask for ticks while spraying")
+	}
+	once := strings.Count(h.CanvasText(), "*")
+	for range 3 * SPRAY_BURST_TICKS {
+		h.Send(NewEventTick())
+	}
+	held := strings.Count(h.CanvasText(), "*")
+	if held <= once {
+		t.Errorf("holding the button still sprayed %d cells after %d, want more", held, once)
+	}
+
+	// Ticks after the button is released spray nothing
+	h.Mouse(pos(10, 7), 0)
+	if h.Editor.WantsTicks() {
+		t.Error("the editor asks for ticks after spraying")
+	}
+	h.Send(NewEventTick())
+	if got := strings.Count(h.CanvasText(), "*"); got != held {
+		t.Errorf("a tick after releasing the button sprayed %d cells, want %d", got, held)
+	}
+}
+
+func TestTickRecording(t *testing.T) {
+	start := time.Now()
+	r, ok := RecordEvent(NewEventTick(), start)
+	if !ok || r.Type != RecordedTick {
+		t.Fatalf("recorded tick as %+v", r)
+	}
+	if _, ok := r.Event().(*EventTick); !ok {
+		t.Errorf("recorded tick is replayed as %T", r.Event())
+	}
+}
@@ -1,11 +1,128 @@
 package main
 
-import "github.com/gdamore/tcell/v2"
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
+
+func init() {
+	RegisterTool(ToolInfo{
+		Name:        "stamp",
+		Action:      action.Paste,
+		Description: "paste the clipboard onto the canvas",
+		Gestures: []string{
+			"click: stamp the clipboard",
+			"click and drag: stamp repeatedly",
+			"right click does not erase; switch to the eraser instead",
+		},
+	})
+}
+
+// Point of the clipboard that is placed under the cursor, in reading order.
+type StampAnchor int
+
+const (
+	AnchorTopLeft StampAnchor = iota
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorCenter
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+var stampAnchorNames = [...]string{
+	"top-left", "top", "top-right",
+	"left", "center", "right",
+	"bottom-left", "bottom", "bottom-right",
+}
+
+func (a StampAnchor) String() string {
+	return stampAnchorNames[a]
+}
+
+func ParseStampAnchor(s string) (StampAnchor, error) {
+	idx := slices.Index(stampAnchorNames[:], s)
+	if idx == -1 {
+		return AnchorCenter, fmt.Errorf("invalid anchor %q", s)
+	}
+	return StampAnchor(idx), nil
+}
+
+// Position of the top-left corner of a clipboard of the given size, relative to the anchor.
+func (a StampAnchor) Offset(w, h int) (int, int) {
+	offset := func(i, size int) int {
+		switch i {
+		case 1:
+			return -size / 2
+		case 2:
+			return -(size - 1)
+		default:
+			return 0
+		}
+	}
+	return offset(int(a)%3, w), offset(int(a)/3, h)
+}
+
+// Parts of the clipboard cells that stamping writes.
+type StampChannel int
+
+const (
+	StampAll StampChannel = iota
+	StampChars
+	StampColors
+)
+
+var stampChannelNames = [...]string{"all", "chars", "colors"}
+
+func (c StampChannel) String() string {
+	return stampChannelNames[c]
+}
+
+func ParseStampChannel(s string) (StampChannel, error) {
+	idx := slices.Index(stampChannelNames[:], s)
+	if idx == -1 {
+		return StampAll, fmt.Errorf("invalid channel %q (want all, chars or colors)", s)
+	}
+	return StampChannel(idx), nil
+}
+
+type StampOptions struct {
+	Anchor StampAnchor
+	// Also write blank cells of the clipboard, instead of letting the canvas show through.
+	Opaque  bool
+	Channel StampChannel
+}
+
+// Adds the locks needed to only write the chosen channel to the editor's lock mask.
+func (o StampOptions) LockMask(mask adraw.LockMask) adraw.LockMask {
+	switch o.Channel {
+	case StampChars:
+		mask |= adraw.LockMaskFg | adraw.LockMaskBg
+	case StampColors:
+		mask |= adraw.LockMaskChar
+	}
+	return mask
+}
+
+func (o StampOptions) String() string {
+	mode := "transparent"
+	if o.Opaque {
+		mode = "opaque"
+	}
+	return fmt.Sprintf("%s, %s, %s", o.Anchor, mode, o.Channel)
+}
 
 type StampTool struct {
 	isDragging   bool
 	hasLastPaint bool
-	lastPaintPos Position
+	lastPaintPos adraw.Position
 }
 
 func (l *StampTool) HandleEvent(m *Editor, event tcell.Event) {
@@ -15,7 +132,6 @@ func (l *StampTool) HandleEvent(m *Editor, event tcell.Event) {
 
 	switch ev := event.(type) {
 	case *tcell.EventMouse:
-		cx, cy := m.cursorX-m.offsetX-m.sx, m.cursorY-m.offsetY-m.sy
 		if ev.Buttons()&tcell.Button1 != 0 {
 			if !l.isDragging {
 				l.isDragging = true
@@ -23,9 +139,15 @@ func (l *StampTool) HandleEvent(m *Editor, event tcell.Event) {
 			}
 
 			m.Stage()
-			p := Position{X: cx, Y: cy}
+			p := m.cursorCanvasPosition()
 			if !l.hasLastPaint || l.lastPaintPos != p {
-				m.stagingCanvas.Stamp(m.clipboard, p.X, p.Y, m.lockMask)
+				dx, dy := m.stamp.Anchor.Offset(m.clipboard.Width, m.clipboard.Height)
+				m.stagingCanvas.Stamp(
+					m.clipboard,
+					adraw.Position{X: p.X + dx, Y: p.Y + dy},
+					m.stamp.LockMask(m.lockMask),
+					m.stamp.Opaque,
+				)
 			}
 			l.hasLastPaint = true
 			l.lastPaintPos = p
@@ -37,27 +159,139 @@ func (l *StampTool) HandleEvent(m *Editor, event tcell.Event) {
 }
 
 func (l *StampTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
-	SetString(p, x+m.sx, y+m.sy-1, "Stamp Tool", tcell.StyleDefault)
+	SetString(p, x+m.sx, y+m.sy-1, fmt.Sprintf("Stamp Tool (%s)", m.stamp), tcell.StyleDefault)
 	if m.clipboard.Width == 0 || m.clipboard.Height == 0 {
 		return
 	}
-	dx, dy := -m.clipboard.Width/2, -m.clipboard.Height/2
+	dx, dy := m.stamp.Anchor.Offset(m.clipboard.Width, m.clipboard.Height)
 
+	canvas := m.CurrentCanvas()
 	crop := &CropPainter{
 		p: p,
-		area: Area{
+		area: adraw.Area{
 			X:      m.offsetX + m.sx,
 			Y:      m.offsetY + m.sy,
-			Width:  m.canvas.Data.Width,
-			Height: m.canvas.Data.Height,
+			Width:  canvas.Data.Width,
+			Height: canvas.Data.Height,
 		},
 	}
-	for y := range m.clipboard.Height {
-		for x := range m.clipboard.Width {
-			c := m.clipboard.MustGet(x, y)
-			if c.Value != ' ' {
-				crop.SetByte(m.cursorX+x+dx, m.cursorY+y+dy, c.Value, c.Style)
+	for cy := range m.clipboard.Height {
+		for cx := range m.clipboard.Width {
+			c := m.clipboard.MustGet(cx, cy)
+			_, bg, _ := c.Style.Decompose()
+			if c.Value == 0 {
+				c.Value = ' '
+			}
+			if m.stamp.Opaque || c.Value != ' ' || bg != tcell.ColorDefault {
+				crop.SetByte(m.cursorX+m.sx+cx+dx, m.cursorY+m.sy+cy+dy, c.Value, m.palette.Style(c.Style))
 			}
 		}
 	}
 }
+
+// Fills the selection with copies of the clipboard, using the stamp options. The copies are
+// aligned to the top-left corner of the selection.
+func (m *Editor) TileFill() {
+	if m.clipboard.Width == 0 || m.clipboard.Height == 0 {
+		m.notification.PushNotification("", "Clipboard is empty", NotificationWarning)
+		return
+	}
+	m.Stage()
+	bounds := m.stagingCanvas.SelectionBounds()
+	m.stagingCanvas.TileFill(
+		m.clipboard,
+		adraw.Position{X: bounds.X, Y: bounds.Y},
+		m.stamp.LockMask(m.lockMask),
+		m.stamp.Opaque,
+	)
+	m.Commit()
+}
+
+// Modal tool for choosing the stamp options.
+type StampOptionsTool struct{}
+
+var (
+	_ Tool = &StampTool{}
+	_ Tool = &StampOptionsTool{}
+)
+
+func (e *StampOptionsTool) HandleEvent(m *Editor, event tcell.Event) {
+	ev, ok := event.(*tcell.EventKey)
+	if !ok {
+		return
+	}
+
+	col, row := int(m.stamp.Anchor)%3, int(m.stamp.Anchor)/3
+	switch ev.Key() {
+	case tcell.KeyLeft:
+		col--
+	case tcell.KeyRight:
+		col++
+	case tcell.KeyUp:
+		row--
+	case tcell.KeyDown:
+		row++
+	case tcell.KeyEnter:
+		m.ClearModalTool()
+		return
+	case tcell.KeyRune:
+		switch r := ev.Rune(); {
+		case r == 'h':
+			col--
+		case r == 'l':
+			col++
+		case r == 'k':
+			row--
+		case r == 'j':
+			row++
+		case r >= '1' && r <= '9':
+			// Laid out like a numeric keypad
+			col, row = int(r-'1')%3, 2-int(r-'1')/3
+		case r == 'o':
+			m.stamp.Opaque = !m.stamp.Opaque
+		case r == 'c':
+			m.stamp.Channel = (m.stamp.Channel + 1) % StampChannel(len(stampChannelNames))
+		}
+	}
+	col, row = max(0, min(2, col)), max(0, min(2, row))
+	m.stamp.Anchor = StampAnchor(row*3 + col)
+}
+
+func (e *StampOptionsTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
+	r := adraw.Area{
+		Width:  40,
+		Height: 9,
+	}
+	r.X = x + (w-r.Width)/2
+	r.Y = y + (h-r.Height)/2
+	bb := adraw.Area{
+		X:      r.X - 1,
+		Y:      r.Y - 1,
+		Width:  r.Width + 2,
+		Height: r.Height + 2,
+	}
+	BorderBox(p, bb, tcell.StyleDefault)
+	FillRegion(p, r.X, r.Y, r.Width, r.Height, ' ', tcell.StyleDefault)
+
+	SetString(p, r.X, r.Y, "Stamp options", tcell.StyleDefault)
+	for a := range StampAnchor(len(stampAnchorNames)) {
+		st := tcell.StyleDefault
+		v := byte('.')
+		if a == m.stamp.Anchor {
+			st = st.Reverse(true)
+			v = '@'
+		}
+		p.SetByte(r.X+1+int(a)%3*2, r.Y+2+int(a)/3, v, st)
+	}
+	SetString(p, r.X+8, r.Y+2, "anchor: "+m.stamp.Anchor.String(), tcell.StyleDefault)
+	opaque := "off"
+	if m.stamp.Opaque {
+		opaque = "on"
+	}
+	SetString(p, r.X+8, r.Y+3, "o: opaque: "+opaque, tcell.StyleDefault)
+	SetString(p, r.X+8, r.Y+4, "c: channel: "+m.stamp.Channel.String(), tcell.StyleDefault)
+
+	hint := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	SetString(p, r.X, r.Y+r.Height-2, "arrows, hjkl, 1-9: move anchor", hint)
+	SetString(p, r.X, r.Y+r.Height-1, "enter, esc: close", hint)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Copies the cells from (0, 0) to (x, y) and switches to the stamp tool.
+func copyTopLeft(h *Harness, x, y int) {
+	h.Press("ctrl+r")
+	h.Drag(pos(0, 0), pos(x, 0), pos(x, y), pos(0, y))
+	h.Press("ctrl+c")
+	h.Press("ctrl+a")
+	h.Press("ctrl+v")
+}
+
+func TestStampAnchors(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("a")
+	h.Click(pos(0, 0))
+	h.Type("b")
+	h.Click(pos(1, 1))
+	copyTopLeft(h, 1, 1)
+
+	h.Click(pos(10, 5))
+	h.RunExLine("stamp anchor top-left")
+	h.Click(pos(14, 5))
+	h.RunExLine("stamp anchor bottom-right")
+	h.Click(pos(4, 8))
+
+	h.AssertCanvas(`
+a
+ b
+
+
+         a
+          b   a
+               b
+   a
+    b`)
+}
+
+func TestStampOpaque(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("x")
+	h.Click(pos(0, 0))
+	copyTopLeft(h, 1, 0)
+	h.RunExLine("stamp anchor top-left")
+
+	// The blank half of the clipboard lets the canvas show through
+	h.Click(pos(6, 0))
+	h.Click(pos(5, 0))
+	h.AssertCanvas(`x    xx`)
+
+	h.RunExLine("stamp opaque on")
+	h.Click(pos(5, 0))
+	h.AssertCanvas(`x    x`)
+}
+
+func TestStampChannels(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("x")
+	h.Click(pos(5, 0))
+	h.RunExLine("set fg red")
+	h.Type("r")
+	h.Click(pos(0, 0))
+	copyTopLeft(h, 0, 0)
+	h.RunExLine("stamp anchor top-left")
+
+	h.RunExLine("stamp channel colors")
+	h.Click(pos(5, 0))
+	c := h.Canvas().Data.MustGet(5, 0)
+	if fg, _, _ := c.Style.Decompose(); c.Value != 'x' || fg != tcell.ColorRed {
+		t.Errorf("colors-only stamp gave %q in %v, want 'x' in red", c.Value, fg)
+	}
+
+	h.RunExLine("stamp channel chars")
+	h.Click(pos(7, 0))
+	c = h.Canvas().Data.MustGet(7, 0)
+	if fg, _, _ := c.Style.Decompose(); c.Value != 'r' || fg != tcell.ColorDefault {
+		t.Errorf("chars-only stamp gave %q in %v, want 'r' in default", c.Value, fg)
+	}
+}
+
+func TestTileFill(t *testing.T) {
+	h := NewHarness(t, 20, 10)
+	h.Type("/")
+	h.Click(pos(0, 0))
+	h.Type("\\")
+	h.Click(pos(1, 0))
+	copyTopLeft(h, 1, 0)
+
+	h.Press("ctrl+r")
+	h.Drag(pos(3, 2), pos(7, 2), pos(7, 3), pos(3, 3))
+	h.Press("alt+t")
+
+	h.AssertCanvas(`
+/\
+
+   /\/\/
+   /\/\/`)
+
+	h.Press("ctrl+z")
+	h.AssertCanvas(`/\`)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
+
+func init() {
+	RegisterTool(ToolInfo{
+		Name:        "symmetry",
+		Action:      action.SymmetryCenter,
+		Description: "move the center of symmetry",
+		Gestures: []string{
+			"click and drag: move the center",
+			"tab: cycle the symmetry mode",
+		},
+	})
+}
+
+// Number of copies made by radial symmetry until it is changed.
+const DEFAULT_SYMMETRY_WAYS = 4
+
+const MAX_SYMMETRY_WAYS = 16
+
+var symmetryGuideStyle = tcell.StyleDefault.Foreground(tcell.ColorYellow)
+
+// Columns or rows that an axis at c runs through: one if it lies on a cell, and the two on
+// either side if it lies between cells.
+func symmetryAxisCells(c float64) []int {
+	lo := int(math.Floor(c))
+	if float64(lo) == c {
+		return []int{lo}
+	}
+	return []int{lo, lo + 1}
+}
+
+// Marks the axes of the symmetry on the border of the canvas, and the center for radial
+// symmetry, with the canvas drawn at offX, offY.
+func (m *Editor) DrawSymmetryGuides(p Painter, offX, offY int) {
+	s := m.symmetry
+	cw, ch := m.CurrentCanvas().Data.Width, m.CurrentCanvas().Data.Height
+	if s.Mode == adraw.SymmetryHorizontal || s.Mode == adraw.SymmetryBoth {
+		for _, x := range symmetryAxisCells(s.CenterX) {
+			p.SetByte(offX+x, offY-1, 'v', symmetryGuideStyle)
+			p.SetByte(offX+x, offY+ch, '^', symmetryGuideStyle)
+		}
+	}
+	if s.Mode == adraw.SymmetryVertical || s.Mode == adraw.SymmetryBoth {
+		for _, y := range symmetryAxisCells(s.CenterY) {
+			p.SetByte(offX-1, offY+y, '>', symmetryGuideStyle)
+			p.SetByte(offX+cw, offY+y, '<', symmetryGuideStyle)
+		}
+	}
+	if s.Mode == adraw.SymmetryRadial {
+		x, y := int(math.Floor(s.CenterX)), int(math.Floor(s.CenterY))
+		p.SetByte(offX+x, offY+y, '+', symmetryGuideStyle)
+	}
+}
+
+type SymmetryTool struct {
+	isDragging bool
+}
+
+var (
+	_ Tool = &SymmetryTool{}
+)
+
+func (s *SymmetryTool) HandleEvent(m *Editor, event tcell.Event) {
+	switch ev := event.(type) {
+	case *tcell.EventMouse:
+		if ev.Buttons()&tcell.Button1 != 0 {
+			s.isDragging = true
+			p := m.cursorCanvasPosition()
+			m.symmetry.CenterX, m.symmetry.CenterY = float64(p.X), float64(p.Y)
+		} else {
+			s.isDragging = false
+		}
+	case *tcell.EventKey:
+		if ev.Key() == tcell.KeyTab {
+			m.symmetry.Mode = (m.symmetry.Mode + 1) % (adraw.SymmetryRadial + 1)
+		}
+	}
+}
+
+func (s *SymmetryTool) Draw(m *Editor, p Painter, x, y, w, h int, lag float64) {
+	sym := m.symmetry
+	title := fmt.Sprintf("Symmetry Tool (%s, center %g, %g)", sym.Mode, sym.CenterX, sym.CenterY)
+	if sym.Mode == adraw.SymmetryRadial {
+		title = fmt.Sprintf("Symmetry Tool (%d-way radial, center %g, %g)", sym.Ways, sym.CenterX, sym.CenterY)
+	}
+	SetString(p, x+m.sx, y+m.sy-1, title, tcell.StyleDefault)
+
+	// Draw the axes across the canvas
+	st := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	cw, ch := m.CurrentCanvas().Data.Width, m.CurrentCanvas().Data.Height
+	ox, oy := m.offsetX+m.sx, m.offsetY+m.sy
+	if sym.Mode == adraw.SymmetryHorizontal || sym.Mode == adraw.SymmetryBoth {
+		for _, cx := range symmetryAxisCells(sym.CenterX) {
+			for cy := range ch {
+				p.SetByte(ox+cx, oy+cy, ':', st)
+			}
+		}
+	}
+	if sym.Mode == adraw.SymmetryVertical || sym.Mode == adraw.SymmetryBoth {
+		for _, cy := range symmetryAxisCells(sym.CenterY) {
+			for cx := range cw {
+				p.SetByte(ox+cx, oy+cy, '-', st)
+			}
+		}
+	}
+	p.SetByte(ox+int(math.Floor(sym.CenterX)), oy+int(math.Floor(sym.CenterY)), '+', symmetryGuideStyle)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestMirroredStroke(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Press("alt+y")
+	h.Type("/")
+	h.Drag(pos(0, 0), pos(2, 0))
+	h.AssertCanvas(`
+///    \\\`)
+
+	// The axis between columns 4 and 5 is marked on the top border of the canvas
+	x, y := h.ScreenPos(pos(4, -1))
+	h.AssertScreenText(x, y, "vv")
+
+	// The stroke and its copy are undone together
+	h.Press("ctrl+z")
+	h.AssertCanvas(``)
+}
+
+func TestMirroredLinePreview(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Press("alt+y")
+	h.Type("/")
+	h.Press("tab")
+	h.Mouse(pos(0, 0), tcell.Button1)
+	h.Mouse(pos(2, 0), tcell.Button1)
+
+	// The line and its copy are previewed before anything is painted
+	h.AssertCanvas(``)
+	x, y := h.ScreenPos(pos(0, 0))
+	h.AssertScreenText(x, y, "///    \\\\\\")
+
+	h.Mouse(pos(2, 0), tcell.ButtonNone)
+	h.AssertCanvas(`
+///    \\\`)
+}
+
+func TestSymmetryCenter(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.Press("alt+Y")
+	h.Click(pos(3, 1))
+	h.Press("esc")
+	h.RunExLine("symmetry both")
+	h.Type("o")
+	h.Click(pos(1, 0))
+	h.AssertCanvas(`
+ o   o
+
+ o   o`)
+
+	h.RunExLine("symmetry center")
+	if got := h.Editor.symmetry; got.CenterX != 4.5 || got.CenterY != 1.5 {
+		t.Errorf("center is %g, %g, want the middle of the canvas", got.CenterX, got.CenterY)
+	}
+}
+
+func TestSymmetryCommand(t *testing.T) {
+	h := NewHarness(t, 10, 4)
+	h.RunExLine("symmetry radial 6")
+	if got := h.Editor.symmetry; got.Mode != adraw.SymmetryRadial || got.Ways != 6 {
+		t.Errorf("symmetry is %v with %d ways, want radial with 6", got.Mode, got.Ways)
+	}
+
+	h.RunExLine("symmetry horizontal 3")
+	h.RunExLine("symmetry center 1.25 0")
+	if got := h.Editor.symmetry; got.Mode != adraw.SymmetryRadial || got.CenterX != 4.5 {
+		t.Errorf("invalid commands changed the symmetry to %+v", got)
+	}
+}
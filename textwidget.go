@@ -89,6 +89,11 @@ func (t *TextWidget) Update() {
 	}
 }
 
+// The caret does not blink, so the text widget only changes in response to events.
+func (t *TextWidget) Animating() bool {
+	return false
+}
+
 func (t *TextWidget) Draw(p Painter, x, y, w, h int, lag float64) {
 	t.EnsureCursorVisible(w)
 	if t.Contents == "" {
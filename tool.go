@@ -1,8 +1,33 @@
 package main
 
-import "github.com/gdamore/tcell/v2"
+import (
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
 
 type Tool interface {
 	HandleEvent(m *Editor, event tcell.Event)
 	Draw(m *Editor, p Painter, x, y, w, h int, lag float64)
 }
+
+// Describes a tool for the help page.
+type ToolInfo struct {
+	Name string
+	// Action that switches to the tool.
+	Action      action.Action
+	Description string
+	// Mouse gestures and keys understood by the tool.
+	Gestures []string
+}
+
+var toolInfos []ToolInfo
+
+// Registers the description of a tool. Called from the init function of each tool.
+func RegisterTool(info ToolInfo) {
+	toolInfos = append(toolInfos, info)
+}
+
+// Returns the descriptions of every registered tool.
+func Tools() []ToolInfo {
+	return toolInfos
+}
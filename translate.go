@@ -1,6 +1,21 @@
 package main
 
-import "github.com/gdamore/tcell/v2"
+import (
+	"github.com/Fekinox/ascii-draw/adraw"
+	action "github.com/Fekinox/ascii-draw/internal"
+	"github.com/gdamore/tcell/v2"
+)
+
+func init() {
+	RegisterTool(ToolInfo{
+		Name:        "translate",
+		Action:      action.Translate,
+		Description: "move the selected characters",
+		Gestures: []string{
+			"click and drag: move the selection",
+		},
+	})
+}
 
 type TranslateTool struct {
 	isDragging bool
@@ -21,7 +36,7 @@ func (l *TranslateTool) HandleEvent(m *Editor, event tcell.Event) {
 			m.stagingCanvas.TranslateBlankTransparent(
 				m.canvas,
 				m.canvas.SelectionMask,
-				Position{},
+				adraw.Position{},
 				cx-l.origX, cy-l.origY,
 			)
 		} else if l.isDragging {
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/terminfo"
+	"golang.org/x/term"
+)
+
+type ColorMode int
+
+const (
+	// Colors are written if the output is a terminal that supports them.
+	ColorAuto ColorMode = iota
+	ColorAlways
+	ColorNever
+)
+
+func ParseColorMode(s string) (ColorMode, error) {
+	switch s {
+	case "auto":
+		return ColorAuto, nil
+	case "always":
+		return ColorAlways, nil
+	case "never":
+		return ColorNever, nil
+	default:
+		return ColorAuto, fmt.Errorf("invalid color mode %q (want auto, always or never)", s)
+	}
+}
+
+// Number of colors to write to the given file, or 0 for none. Follows the NO_COLOR
+// convention and treats dumb terminals and non-terminals as colorless.
+func (c ColorMode) Colors(f *os.File) int {
+	switch c {
+	case ColorAlways:
+		return max(TerminalColors(), 8)
+	case ColorNever:
+		return 0
+	}
+
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return 0
+	}
+	if t := os.Getenv("TERM"); t == "" || t == "dumb" {
+		return 0
+	}
+	if !term.IsTerminal(int(f.Fd())) {
+		return 0
+	}
+	return TerminalColors()
+}
+
+// Number of colors that the terminal named by $TERM shows, as given by its terminfo entry or
+// by $COLORTERM for 24-bit color. Unknown terminals are assumed to have the 16 ANSI colors.
+func TerminalColors() int {
+	ti, err := terminfo.LookupTerminfo(os.Getenv("TERM"))
+	if err != nil {
+		switch os.Getenv("COLORTERM") {
+		case "truecolor", "24bit":
+			return adraw.TrueColor
+		}
+		return 16
+	}
+	if ti.TrueColor || ti.SetFgRGB != "" {
+		return adraw.TrueColor
+	}
+	return ti.Colors
+}
+
+func runCat(c *Subcommand, args []string) error {
+	fs := c.flagSet()
+	x := fs.Int("x", 0, "left edge of the region to print")
+	y := fs.Int("y", 0, "top edge of the region to print")
+	w := fs.Int("w", 0, "width of the region to print; 0 for the rest of the drawing")
+	h := fs.Int("h", 0, "height of the region to print; 0 for the rest of the drawing")
+	fit := fs.Bool("fit", false, "crop the drawing to the width of the terminal")
+	colorFlag := fs.String("color", "auto", "when to print colors: auto, always or never")
+	pager := fs.Bool("pager", false, "browse the drawing in an interactive pager")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("cat takes a single file")
+	}
+
+	colorMode, err := ParseColorMode(*colorFlag)
+	if err != nil {
+		return err
+	}
+
+	b, _, err := adraw.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	region := adraw.Area{X: *x, Y: *y, Width: *w, Height: *h}
+	if region.Width <= 0 {
+		region.Width = b.Data.Width - region.X
+	}
+	if region.Height <= 0 {
+		region.Height = b.Data.Height - region.Y
+	}
+	if *fit {
+		if tw, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			region.Width = min(region.Width, tw)
+		}
+	}
+	b = b.Crop(region)
+
+	if *pager {
+		return RunPager(b, fs.Arg(0))
+	}
+
+	if colors := colorMode.Colors(os.Stdout); colors > 0 {
+		return b.ExportANSIColors(os.Stdout, colors)
+	}
+	if err := b.Export(os.Stdout); err != nil {
+		return err
+	}
+	_, err = io.WriteString(os.Stdout, "\n")
+	return err
+}
+
+// Shows the buffer in a full-screen pager that can be scrolled with the arrow keys, hjkl,
+// page up/down and home/end.
+func RunPager(b *adraw.Buffer, title string) error {
+	s, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	if err := s.Init(); err != nil {
+		return err
+	}
+	defer s.Fini()
+
+	var offX, offY int
+	for {
+		sw, sh := s.Size()
+		// The last line is reserved for the status bar
+		viewH := max(1, sh-1)
+		offX = max(0, min(offX, b.Data.Width-sw))
+		offY = max(0, min(offY, b.Data.Height-viewH))
+
+		s.Clear()
+		b.Crop(adraw.Area{X: offX, Y: offY, Width: sw, Height: viewH}).Render(s, 0, 0, true)
+		status := fmt.Sprintf(
+			" %s  %dx%d  lines %d-%d  (q to quit)",
+			title, b.Data.Width, b.Data.Height,
+			offY+1, min(b.Data.Height, offY+viewH),
+		)
+		for i, r := range status {
+			if i >= sw {
+				break
+			}
+			s.SetContent(i, sh-1, r, nil, tcell.StyleDefault.Reverse(true))
+		}
+		s.Show()
+
+		switch ev := s.PollEvent().(type) {
+		case nil:
+			return nil
+		case *tcell.EventResize:
+			s.Sync()
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEscape, tcell.KeyCtrlC:
+				return nil
+			case tcell.KeyUp:
+				offY--
+			case tcell.KeyDown:
+				offY++
+			case tcell.KeyLeft:
+				offX--
+			case tcell.KeyRight:
+				offX++
+			case tcell.KeyPgUp:
+				offY -= viewH
+			case tcell.KeyPgDn:
+				offY += viewH
+			case tcell.KeyHome:
+				offX, offY = 0, 0
+			case tcell.KeyEnd:
+				offY = b.Data.Height
+			case tcell.KeyRune:
+				switch ev.Rune() {
+				case 'q':
+					return nil
+				case 'k':
+					offY--
+				case 'j':
+					offY++
+				case ' ':
+					offY += viewH
+				case 'h':
+					offX--
+				case 'l':
+					offX++
+				case 'g':
+					offX, offY = 0, 0
+				case 'G':
+					offY = b.Data.Height
+				}
+			}
+		}
+	}
+}
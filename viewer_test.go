@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Fekinox/ascii-draw/adraw"
+)
+
+func TestTerminalColors(t *testing.T) {
+	t.Setenv("TCELL_TRUECOLOR", "")
+	tests := []struct {
+		term, colorterm string
+		want            int
+	}{
+		{"xterm", "", 8},
+		{"xterm-256color", "", 256},
+		{"no-such-terminal", "", 16},
+		{"no-such-terminal", "truecolor", adraw.TrueColor},
+		{"screen-256color", "truecolor", adraw.TrueColor},
+	}
+	for _, tt := range tests {
+		t.Setenv("TERM", tt.term)
+		t.Setenv("COLORTERM", tt.colorterm)
+		if got := TerminalColors(); got != tt.want {
+			t.Errorf("TERM=%s COLORTERM=%s: %d colors, want %d", tt.term, tt.colorterm, got, tt.want)
+		}
+	}
+
+	t.Setenv("TERM", "xterm")
+	t.Setenv("COLORTERM", "")
+	if got := ColorNever.Colors(nil); got != 0 {
+		t.Errorf("never gives %d colors", got)
+	}
+	if got := ColorAlways.Colors(nil); got != 8 {
+		t.Errorf("always gives %d colors on xterm, want 8", got)
+	}
+}
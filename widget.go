@@ -6,6 +6,9 @@ type Widget interface {
 	HandleEvent(event tcell.Event)
 	Update()
 	Draw(p Painter, x, y, w, h int, lag float64)
+	// Reports whether the widget needs to be updated and redrawn on every tick, even if no
+	// events arrive.
+	Animating() bool
 }
 
 type ToggleableWidget struct {
@@ -23,6 +26,10 @@ func (t *ToggleableWidget) Update() {
 	}
 }
 
+func (t *ToggleableWidget) Animating() bool {
+	return !t.disabled && t.Widget.Animating()
+}
+
 func (t *ToggleableWidget) Draw(p Painter, x, y, w, h int, lag float64) {
 	if !t.disabled {
 		t.Widget.Draw(p, x, y, w, h, lag)